@@ -3,6 +3,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -12,6 +13,7 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -56,6 +58,18 @@ var versionCmd = &cobra.Command{
 	},
 }
 
+var discoverCmd = &cobra.Command{
+	Use:   "discover [root]",
+	Short: "List the Beads projects under a directory",
+	Long: `Walk down from root (default: the current directory) and list every
+.beads directory, skipping what .gitignore files ignore.
+
+Serve them all together with 'seebeads serve --discover [root]', or save
+the list with --json as a workspace file for 'seebeads serve --workspace'.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runDiscover,
+}
+
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize a new Beads project and optionally open dashboard",
@@ -73,27 +87,71 @@ var (
 	flagNoWatch    bool
 	flagAgentMode  bool
 	flagInitPath   string
+	flagMaxBeadMiB int64
+	flagHeartbeat  time.Duration
+	flagTokens     string
+	flagNoAuth     bool
+	flagTLSCert    string
+	flagTLSKey     string
+	flagSelfSigned bool
+	flagHosts      []string
+	flagOrigins    []string
+	flagProjects   []string
+	flagWorkspace  string
+	flagDiscover   string
+	flagDepth      int
+	flagJSON       bool
+	flagMetrics    bool
 )
 
 func init() {
 	rootCmd.AddCommand(serveCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(discoverCmd)
 
 	serveCmd.Flags().IntVarP(&flagPort, "port", "p", 3456, "Port to listen on")
 	serveCmd.Flags().StringVarP(&flagHost, "host", "H", "127.0.0.1", "Host to bind to")
 	serveCmd.Flags().BoolVarP(&flagOpen, "open", "o", false, "Open browser automatically")
 	serveCmd.Flags().BoolVar(&flagNoWatch, "no-watch", false, "Disable file watching")
 	serveCmd.Flags().BoolVar(&flagAgentMode, "agent-mode", false, "Start with Agent Mode enabled")
+	serveCmd.Flags().Int64Var(&flagMaxBeadMiB, "max-bead-size", beads.DefaultMaxBeadSize>>20, "Largest single bead record to load, in MiB (0 for no limit)")
+	serveCmd.Flags().DurationVar(&flagHeartbeat, "heartbeat", 30*time.Second, "How often to send heartbeats on idle event streams")
+	serveCmd.Flags().StringVar(&flagTokens, "tokens", "", "Access tokens file (defaults to .beads/"+config.TokensFileName+", then seebeads/tokens in the user config directory, if either exists)")
+	serveCmd.Flags().BoolVar(&flagNoAuth, "no-auth", false, "Allow serving beyond localhost without access tokens")
+	serveCmd.Flags().StringVar(&flagTLSCert, "tls-cert", "", "Serve HTTPS with this certificate file")
+	serveCmd.Flags().StringVar(&flagTLSKey, "tls-key", "", "Private key file for --tls-cert")
+	serveCmd.Flags().BoolVar(&flagSelfSigned, "tls-self-signed", false, "Serve HTTPS with a self-signed development certificate cached in .beads/.seebeads")
+	serveCmd.Flags().StringSliceVar(&flagHosts, "allowed-host", nil, "Extra host names the dashboard may be reached by (\"*\" for any)")
+	serveCmd.Flags().StringSliceVar(&flagOrigins, "allowed-origin", config.DefaultAllowedOrigins, "Origins allowed to make cross-origin and state-changing requests")
+	serveCmd.Flags().StringArrayVar(&flagProjects, "project", nil, "Serve several projects together: name=path to a project or its .beads directory (repeatable)")
+	serveCmd.Flags().StringVar(&flagWorkspace, "workspace", "", "Workspace file listing projects to serve together")
+	serveCmd.Flags().StringVar(&flagDiscover, "discover", "", "Serve every Beads project found under this directory")
+	serveCmd.Flags().IntVar(&flagDepth, "depth", config.DefaultDiscoverDepth, "How many directories below --discover to look")
+	serveCmd.Flags().BoolVar(&flagMetrics, "metrics", false, "Serve Prometheus metrics at /metrics")
+
+	discoverCmd.Flags().IntVar(&flagDepth, "depth", config.DefaultDiscoverDepth, "How many directories below root to look")
+	discoverCmd.Flags().BoolVar(&flagJSON, "json", false, "Print a workspace file instead of a table")
 
 	initCmd.Flags().BoolVarP(&flagOpen, "open", "o", false, "Open dashboard after initialization")
 	initCmd.Flags().StringVarP(&flagInitPath, "path", "p", "", "Directory to initialize (defaults to current directory)")
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
-	// Find .beads directory
-	beadsDir, err := config.FindBeadsDir()
+	// Projects to serve together, if any were given
+	projects, err := workspaceProjects()
 	if err != nil {
+		return err
+	}
+
+	// Find .beads directory
+	var beadsDir string
+	if len(projects) > 0 {
+		beadsDir, err = projects[0].BeadsDir()
+		if err != nil {
+			return err
+		}
+	} else if beadsDir, err = config.FindBeadsDir(); err != nil {
 		fmt.Fprintln(os.Stderr, "")
 		fmt.Fprintln(os.Stderr, "  No Beads project found.")
 		fmt.Fprintln(os.Stderr, "")
@@ -122,6 +180,26 @@ func runServe(cmd *cobra.Command, args []string) error {
 		NoWatch:     flagNoWatch,
 		BeadsPath:   beadsDir,
 		UseSQLite:   useSQLite,
+		MaxBeadSize: flagMaxBeadMiB << 20,
+
+		HeartbeatInterval: flagHeartbeat,
+		TokensPath:        flagTokens,
+		NoAuth:            flagNoAuth,
+		Metrics:           flagMetrics,
+
+		TLSCert:       flagTLSCert,
+		TLSKey:        flagTLSKey,
+		TLSSelfSigned: flagSelfSigned,
+
+		AllowedHosts:   flagHosts,
+		AllowedOrigins: flagOrigins,
+
+		Projects: projects,
+	}
+	if cfg.TokensPath == "" && !cfg.NoAuth {
+		if cfg.TokensPath, err = config.FindTokensFile(beadsDir); err != nil {
+			return err
+		}
 	}
 	if useSQLite {
 		cfg.DBPath = dataPath
@@ -133,22 +211,26 @@ func runServe(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	// Build the graph from SQLite or JSONL
-	var graph *beads.BeadsGraph
-	if useSQLite {
-		log.Printf("Loading beads from %s (SQLite)...", dataPath)
-		graph, err = beads.BuildGraphFromSQLite(dataPath)
-	} else {
-		log.Printf("Loading beads from %s (JSONL)...", dataPath)
-		graph, err = beads.BuildGraph(dataPath)
-	}
+	graph, err := loadGraph(dataPath, useSQLite, cfg.MaxBeadSize)
 	if err != nil {
-		return fmt.Errorf("failed to build graph: %w", err)
+		return err
 	}
-	log.Printf("Loaded %d beads", len(graph.Beads))
 
-	// Create and start server
-	srv := server.New(cfg, graph, version)
+	// Create and start server, loading the other projects in a workspace
+	var srv *server.Server
+	if len(projects) > 0 {
+		served := []*server.Project{{Name: projects[0].Name, Graph: graph}}
+		for _, project := range projects[1:] {
+			p, err := loadProject(project, cfg.MaxBeadSize)
+			if err != nil {
+				return err
+			}
+			served = append(served, p)
+		}
+		srv = server.NewWorkspace(cfg, served, version)
+	} else {
+		srv = server.New(cfg, graph, version)
+	}
 
 	// Handle graceful shutdown
 	stop := make(chan os.Signal, 1)
@@ -172,7 +254,10 @@ func runServe(cmd *cobra.Command, args []string) error {
 	fmt.Printf("  │   ➜  Local: \033[36m%s\033[0m           │\n", url)
 	fmt.Println("  │                                             │")
 	fmt.Printf("  │   📁 %s                │\n", truncatePath(dataPath, 30))
-	fmt.Printf("  │   📊 %d beads loaded                        │\n", len(graph.Beads))
+	fmt.Printf("  │   📊 %d beads loaded                        │\n", len(graph.Snapshot().Beads))
+	if len(projects) > 1 {
+		fmt.Printf("  │   🗂  %d projects under /api/projects        │\n", len(projects))
+	}
 	if flagAgentMode {
 		fmt.Println("  │   🤖 Agent Mode: enabled                    │")
 	}
@@ -199,6 +284,133 @@ func runServe(cmd *cobra.Command, args []string) error {
 	}
 }
 
+// workspaceProjects returns the projects given with --workspace and --project
+func workspaceProjects() ([]config.Project, error) {
+	var projects []config.Project
+	if flagWorkspace != "" {
+		loaded, err := config.LoadWorkspace(flagWorkspace)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, loaded...)
+	}
+	for _, value := range flagProjects {
+		project, err := config.ParseProject(value)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	if flagDiscover != "" {
+		found, err := config.Discover(flagDiscover, flagDepth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover projects: %w", err)
+		}
+		for _, project := range found {
+			if project.DataErr != nil {
+				log.Printf("Skipping %s: no data file", project.Rel)
+				continue
+			}
+			projects = append(projects, project.Project)
+		}
+		if len(projects) == 0 {
+			return nil, fmt.Errorf("no Beads projects found under %s", flagDiscover)
+		}
+	}
+	return projects, nil
+}
+
+func runDiscover(cmd *cobra.Command, args []string) error {
+	root := "."
+	if len(args) > 0 {
+		root = args[0]
+	}
+
+	found, err := config.Discover(root, flagDepth)
+	if err != nil {
+		return err
+	}
+
+	if flagJSON {
+		// Paths are relative to root, so save the file there
+		workspace := config.Workspace{Projects: []config.Project{}}
+		for _, project := range found {
+			if project.DataErr == nil {
+				workspace.Projects = append(workspace.Projects, config.Project{Name: project.Name, Path: project.Rel})
+			}
+		}
+		data, err := json.MarshalIndent(workspace, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(found) == 0 {
+		fmt.Printf("No Beads projects found under %s\n", root)
+		return nil
+	}
+
+	fmt.Printf("Found %d Beads project(s) under %s:\n\n", len(found), root)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  NAME\tPATH\tDATA")
+	for _, project := range found {
+		data := "no data file - run 'bd init'"
+		if project.DataErr == nil {
+			data = filepath.Base(project.DataPath)
+			if project.UseSQLite {
+				data += " (SQLite)"
+			} else {
+				data += " (JSONL)"
+			}
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", project.Name, project.Rel, data)
+	}
+	tw.Flush()
+
+	fmt.Println("")
+	fmt.Printf("Serve them together with: seebeads serve --discover %s\n", root)
+	return nil
+}
+
+// loadProject builds the graph of one project in a workspace
+func loadProject(project config.Project, maxBeadSize int64) (*server.Project, error) {
+	beadsDir, err := project.BeadsDir()
+	if err != nil {
+		return nil, err
+	}
+	dataPath, useSQLite, err := config.FindBeadsDataPath(beadsDir)
+	if err != nil {
+		return nil, fmt.Errorf("project %s: %w", project.Name, err)
+	}
+	graph, err := loadGraph(dataPath, useSQLite, maxBeadSize)
+	if err != nil {
+		return nil, fmt.Errorf("project %s: %w", project.Name, err)
+	}
+	return &server.Project{Name: project.Name, Graph: graph}, nil
+}
+
+// loadGraph builds a graph from SQLite or JSONL
+func loadGraph(dataPath string, useSQLite bool, maxBeadSize int64) (*beads.BeadsGraph, error) {
+	var source beads.DataSource
+	if useSQLite {
+		log.Printf("Loading beads from %s (SQLite)...", dataPath)
+		source = beads.NewSQLiteSource(dataPath)
+	} else {
+		log.Printf("Loading beads from %s (JSONL)...", dataPath)
+		jsonl := beads.NewJSONLSource(dataPath)
+		jsonl.Options.MaxBeadSize = maxBeadSize
+		source = jsonl
+	}
+	graph, err := beads.BuildGraphFromSource(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build graph: %w", err)
+	}
+	log.Printf("Loaded %d beads", len(graph.Snapshot().Beads))
+	return graph, nil
+}
+
 func openBrowser(url string) {
 	var cmd *exec.Cmd
 
@@ -276,6 +488,20 @@ func runInit(cmd *cobra.Command, args []string) error {
 		flagHost = "127.0.0.1"
 		flagNoWatch = false
 		flagAgentMode = false
+		flagMaxBeadMiB = beads.DefaultMaxBeadSize >> 20
+		flagHeartbeat = 30 * time.Second
+		flagTokens = ""
+		flagNoAuth = false
+		flagTLSCert = ""
+		flagTLSKey = ""
+		flagSelfSigned = false
+		flagHosts = nil
+		flagOrigins = config.DefaultAllowedOrigins
+		flagProjects = nil
+		flagWorkspace = ""
+		flagDiscover = ""
+		flagDepth = config.DefaultDiscoverDepth
+		flagMetrics = false
 		flagOpen = true
 		
 		return runServe(cmd, args)
@@ -0,0 +1,208 @@
+package beads
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ProjectGraph names one project's graph in an aggregate
+type ProjectGraph struct {
+	Name  string
+	Graph *BeadsGraph
+}
+
+// AggregateSource merges the current snapshots of several projects' graphs
+// into one, so dependencies between projects resolve. Every bead keeps its ID
+// and gets its Project set. A reference to an ID that isn't in the bead's own
+// project is routed to the project that owns the ID's prefix (api-123 to the
+// project whose beads are api-*). IDs used by more than one project are
+// qualified as "<project>:<id>".
+//
+// It reads the project graphs rather than their files, so it has nothing to
+// watch; rebuild the aggregate graph whenever a project's graph reloads.
+type AggregateSource struct {
+	projects []ProjectGraph
+
+	mu       sync.Mutex
+	prefixes map[string]string // ID prefix -> project, from the latest load
+}
+
+// NewAggregateSource creates a DataSource over the given project graphs
+func NewAggregateSource(projects []ProjectGraph) *AggregateSource {
+	return &AggregateSource{projects: projects}
+}
+
+// BuildAggregateGraph builds a graph merging the given project graphs
+func BuildAggregateGraph(projects []ProjectGraph) (*BeadsGraph, error) {
+	return BuildGraphFromSource(NewAggregateSource(projects))
+}
+
+// Load merges the projects' current beads. Parse errors and conflicts stay
+// with each project's own graph.
+func (a *AggregateSource) Load() (*ParseResult, error) {
+	// Pin one snapshot per project for the whole merge
+	snaps := make([]*Snapshot, len(a.projects))
+	owners := make(map[string][]string) // ID -> projects that have it
+	for i, project := range a.projects {
+		snaps[i] = project.Graph.Snapshot()
+		for id := range snaps[i].Beads {
+			owners[id] = append(owners[id], project.Name)
+		}
+	}
+
+	r := &idRouter{owners: owners, prefixes: ownPrefixes(a.projects, snaps)}
+	result := &ParseResult{}
+	for i, project := range a.projects {
+		for _, bead := range snaps[i].Beads {
+			result.Beads = append(result.Beads, r.rewrite(bead, project.Name))
+		}
+		result.FileSize += snaps[i].FileSize
+	}
+
+	a.mu.Lock()
+	a.prefixes = r.prefixes
+	a.mu.Unlock()
+	return result, nil
+}
+
+// Describe names the merged projects
+func (a *AggregateSource) Describe() string {
+	names := make([]string, len(a.projects))
+	for i, project := range a.projects {
+		names[i] = project.Name
+	}
+	return fmt.Sprintf("%d projects (%s)", len(names), strings.Join(names, ", "))
+}
+
+// File returns "": the aggregate reads graphs, not a file
+func (a *AggregateSource) File() string {
+	return ""
+}
+
+// Backend returns "aggregate"
+func (a *AggregateSource) Backend() string {
+	return "aggregate"
+}
+
+// WatchTargets returns nothing: the aggregate is rebuilt when its projects reload
+func (a *AggregateSource) WatchTargets() []string {
+	return nil
+}
+
+// Prefixes returns which project each ID prefix is routed to
+func (a *AggregateSource) Prefixes() map[string]string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	prefixes := make(map[string]string, len(a.prefixes))
+	for prefix, project := range a.prefixes {
+		prefixes[prefix] = project
+	}
+	return prefixes
+}
+
+// IDPrefix returns the prefix of a bd-style ID: "api-a1b2.3" -> "api"
+func IDPrefix(id string) string {
+	if dot := strings.Index(id, "."); dot != -1 {
+		id = id[:dot]
+	}
+	if dash := strings.LastIndex(id, "-"); dash > 0 {
+		return id[:dash]
+	}
+	return ""
+}
+
+// ownPrefixes assigns each ID prefix to the project with the most beads using
+// it, the earlier project winning ties
+func ownPrefixes(projects []ProjectGraph, snaps []*Snapshot) map[string]string {
+	type owner struct {
+		project string
+		count   int
+	}
+	counts := make(map[string]*owner)
+	for i, project := range projects {
+		perPrefix := make(map[string]int)
+		for id := range snaps[i].Beads {
+			if prefix := IDPrefix(id); prefix != "" {
+				perPrefix[prefix]++
+			}
+		}
+		names := make([]string, 0, len(perPrefix))
+		for prefix := range perPrefix {
+			names = append(names, prefix)
+		}
+		sort.Strings(names)
+		for _, prefix := range names {
+			if best := counts[prefix]; best == nil || perPrefix[prefix] > best.count {
+				counts[prefix] = &owner{project: project.Name, count: perPrefix[prefix]}
+			}
+		}
+	}
+
+	prefixes := make(map[string]string, len(counts))
+	for prefix, owner := range counts {
+		prefixes[prefix] = owner.project
+	}
+	return prefixes
+}
+
+// idRouter maps IDs as seen from one project to IDs in the aggregate
+type idRouter struct {
+	owners   map[string][]string
+	prefixes map[string]string
+}
+
+// qualify returns the aggregate ID of id in project
+func (r *idRouter) qualify(project, id string) string {
+	if len(r.owners[id]) > 1 {
+		return project + ":" + id
+	}
+	return id
+}
+
+// resolve returns the aggregate ID that a reference from project points to.
+// References resolve within their own project first, then to the project
+// owning the ID's prefix. Anything else, an ID only one project has or a
+// dangling reference, is left as it is.
+func (r *idRouter) resolve(project, ref string) string {
+	owners := r.owners[ref]
+	if containsString(owners, project) {
+		return r.qualify(project, ref)
+	}
+	if owner, ok := r.prefixes[IDPrefix(ref)]; ok && containsString(owners, owner) {
+		return r.qualify(owner, ref)
+	}
+	return ref
+}
+
+// rewrite returns a detached copy of a project's bead with its ID and
+// references mapped into the aggregate. Dependencies are copied too, since
+// they belong to the project's published snapshot.
+func (r *idRouter) rewrite(bead *Bead, project string) *Bead {
+	routed := bead.detach()
+	routed.Project = project
+	routed.fingerprint = 0 // References depend on the other projects too
+	routed.ID = r.qualify(project, bead.ID)
+	if bead.ParentID != "" {
+		routed.ParentID = r.resolve(project, bead.ParentID)
+	}
+
+	routed.BlockerIDs = nil
+	for _, id := range bead.BlockerIDs {
+		routed.BlockerIDs = append(routed.BlockerIDs, r.resolve(project, id))
+	}
+
+	routed.Dependencies = nil
+	for _, dep := range bead.Dependencies {
+		edge := *dep
+		if dep.IssueID != "" {
+			edge.IssueID = routed.ID
+		}
+		if dep.DependsOnID != "" {
+			edge.DependsOnID = r.resolve(project, dep.DependsOnID)
+		}
+		routed.Dependencies = append(routed.Dependencies, &edge)
+	}
+	return routed
+}
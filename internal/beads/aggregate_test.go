@@ -0,0 +1,151 @@
+package beads
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// blockedRecord returns a JSONL line for a bead blocked by the given IDs
+func blockedRecord(id string, blockers ...string) string {
+	var deps []string
+	for _, blocker := range blockers {
+		deps = append(deps, fmt.Sprintf(`{"issue_id":%q,"depends_on_id":%q,"type":"blocks"}`, id, blocker))
+	}
+	return fmt.Sprintf(`{"id":%q,"title":%q,"status":"open","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[%s]}`+"\n",
+		id, id, strings.Join(deps, ","))
+}
+
+// buildTestAggregate merges projects built from JSONL lines, in name order
+func buildTestAggregate(t *testing.T, projects map[string][]string) *BeadsGraph {
+	t.Helper()
+	names := make([]string, 0, len(projects))
+	for name := range projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var graphs []ProjectGraph
+	for _, name := range names {
+		graph, err := BuildGraph(writeJSONL(t, projects[name]...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		graphs = append(graphs, ProjectGraph{Name: name, Graph: graph})
+	}
+	graph, err := BuildAggregateGraph(graphs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return graph
+}
+
+func TestAggregateRoutesReferences(t *testing.T) {
+	graph := buildTestAggregate(t, map[string][]string{
+		"api": {
+			blockedRecord("api-1", "web-1"),          // Only in web
+			blockedRecord("api-2", "bd-1"),           // In both, resolves to its own project
+			blockedRecord("api-3", "web-9"),          // In docs and web, routed by prefix
+			blockedRecord("api-4", "ui-1", "zz-404"), // Dangling references stay as they are
+			blockedRecord("api-5"),
+			blockedRecord("bd-1"),
+			blockedRecord("api-1.1"),
+		},
+		"docs": {
+			blockedRecord("docs-1", "web-9"), // Its own copy
+			blockedRecord("web-9"),
+		},
+		"web": {
+			blockedRecord("web-1", "api-5"),
+			blockedRecord("web-2", "bd-1"),
+			blockedRecord("web-9"),
+			blockedRecord("bd-1"),
+		},
+	})
+	snap := graph.Snapshot()
+
+	tests := []struct {
+		id       string
+		project  string
+		blockers string // Resolved blocker IDs
+		linked   int    // Blockers that resolved to a bead
+	}{
+		{"api-1", "api", "web-1", 1},
+		{"api-2", "api", "api:bd-1", 1},
+		{"api-3", "api", "web:web-9", 1},
+		{"api-4", "api", "ui-1,zz-404", 0},
+		{"web-1", "web", "api-5", 1},
+		{"web-2", "web", "web:bd-1", 1},
+		{"api:bd-1", "api", "", 0},
+		{"web:web-9", "web", "", 0},
+		{"docs-1", "docs", "docs:web-9", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			bead := snap.Beads[tt.id]
+			if bead == nil {
+				t.Fatalf("%s missing from the aggregate", tt.id)
+			}
+			if bead.Project != tt.project {
+				t.Errorf("project = %q, want %q", bead.Project, tt.project)
+			}
+			if got := strings.Join(bead.BlockerIDs, ","); got != tt.blockers {
+				t.Errorf("blockers = %s, want %s", got, tt.blockers)
+			}
+			for _, dep := range bead.Dependencies {
+				if dep.IssueID != tt.id {
+					t.Errorf("dependency issue ID = %q", dep.IssueID)
+				}
+			}
+			if len(bead.Blockers) != tt.linked {
+				t.Errorf("linked blockers = %d, want %d", len(bead.Blockers), tt.linked)
+			}
+		})
+	}
+
+	// web-1 is blocked across projects, so api-1 can't start
+	if snap.Beads["api-1"].IsReady() {
+		t.Error("api-1 ready while blocked by web-1")
+	}
+	if parent := snap.Beads["api-1.1"].Parent; parent == nil || parent.ID != "api-1" {
+		t.Errorf("api-1.1 parent = %v", parent)
+	}
+}
+
+func TestAggregatePrefixOwners(t *testing.T) {
+	graph := buildTestAggregate(t, map[string][]string{
+		"api": {blockedRecord("api-1"), blockedRecord("api-2"), blockedRecord("web-9"), blockedRecord("ui-1")},
+		"web": {blockedRecord("web-1"), blockedRecord("web-2"), blockedRecord("ui-2")},
+	})
+	prefixes := graph.Source.(*AggregateSource).Prefixes()
+
+	// Most beads wins; the earlier project wins a tie
+	want := map[string]string{"api": "api", "web": "web", "ui": "api"}
+	if len(prefixes) != len(want) {
+		t.Errorf("prefixes = %v, want %v", prefixes, want)
+	}
+	for prefix, project := range want {
+		if prefixes[prefix] != project {
+			t.Errorf("prefix %s owned by %q, want %q", prefix, prefixes[prefix], project)
+		}
+	}
+}
+
+func TestIDPrefix(t *testing.T) {
+	tests := []struct {
+		id     string
+		prefix string
+	}{
+		{"api-a1b2", "api"},
+		{"api-a1b2.3", "api"},
+		{"my-app-12", "my-app"},
+		{"noprefix", ""},
+		{"-1", ""},
+	}
+	for _, tt := range tests {
+		if got := IDPrefix(tt.id); got != tt.prefix {
+			t.Errorf("IDPrefix(%q) = %q, want %q", tt.id, got, tt.prefix)
+		}
+	}
+}
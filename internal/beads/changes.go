@@ -0,0 +1,262 @@
+package beads
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// ChangeType identifies what happened to a bead between two snapshots
+type ChangeType string
+
+const (
+	ChangeCreated           ChangeType = "bead.created"
+	ChangeUpdated           ChangeType = "bead.updated"
+	ChangeClosed            ChangeType = "bead.closed"
+	ChangeReopened          ChangeType = "bead.reopened"
+	ChangeDeleted           ChangeType = "bead.deleted"
+	ChangeDependencyAdded   ChangeType = "dependency.added"
+	ChangeDependencyRemoved ChangeType = "dependency.removed"
+)
+
+// Change is one difference between two snapshots
+type Change struct {
+	Type ChangeType `json:"type"`
+	ID   string     `json:"id"`
+
+	// Bead is the new state of the bead, or its last known state for deletions
+	Bead *Bead `json:"bead"`
+
+	// Previous is the state before the change, for beads that exist in both
+	// snapshots. Subscribers use it to notice a bead leaving their filter.
+	Previous *Bead `json:"-"`
+
+	// Fields holds the new value of every stored field that changed, keyed by
+	// its JSON name. Only set for updated, closed and reopened beads.
+	Fields map[string]interface{} `json:"fields,omitempty"`
+
+	// Dependency is the edge that was added or removed
+	Dependency *Dependency `json:"dependency,omitempty"`
+
+	// beads is the map of the snapshot the change leads to, for filters that
+	// look at other beads
+	beads map[string]*Bead
+}
+
+// Matches reports whether the change concerns a bead that passes the filter,
+// either before or after the change
+func (c *Change) Matches(filter *Filter) bool {
+	if filter.Matches(c.Bead, c.beads) {
+		return true
+	}
+	return c.Previous != nil && filter.Matches(c.Previous, c.beads)
+}
+
+// Diff returns the changes that turn old into next, sorted by bead ID.
+// Dependency edges are reported separately from field changes, and only for
+// beads that exist in both snapshots; created and deleted beads carry theirs
+// in Bead.
+//
+// A snapshot merged from appended records only compares the IDs they
+// touched. Otherwise every bead is visited, but beads read from an
+// identical record are skipped without comparing fields.
+func Diff(old, next *Snapshot) []*Change {
+	var changes []*Change
+	compare := func(id string) {
+		bead, ok := next.Beads[id]
+		prev, existed := old.Beads[id]
+		switch {
+		case ok && !existed:
+			changes = append(changes, &Change{Type: ChangeCreated, ID: id, Bead: bead})
+		case !ok && existed:
+			changes = append(changes, &Change{Type: ChangeDeleted, ID: id, Bead: prev})
+		case ok && !sameRecord(prev, bead):
+			changes = append(changes, diffBead(prev, bead)...)
+		}
+	}
+
+	if next.touched != nil {
+		for id := range next.touched {
+			compare(id)
+		}
+	} else {
+		for id := range next.Beads {
+			compare(id)
+		}
+		for id := range old.Beads {
+			if _, ok := next.Beads[id]; !ok {
+				compare(id)
+			}
+		}
+	}
+
+	for _, change := range changes {
+		change.beads = next.Beads
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].ID != changes[j].ID {
+			return changes[i].ID < changes[j].ID
+		}
+		return changes[i].Type < changes[j].Type
+	})
+	return changes
+}
+
+// sameRecord reports whether two beads were read from identical records
+func sameRecord(prev, bead *Bead) bool {
+	if prev == bead {
+		return true
+	}
+	return prev.fingerprint != 0 && prev.fingerprint == bead.fingerprint && prev.Conflicted == bead.Conflicted
+}
+
+// diffBead compares two versions of the same bead
+func diffBead(prev, bead *Bead) []*Change {
+	var changes []*Change
+	if fields := ChangedFields(prev, bead); len(fields) > 0 {
+		changeType := ChangeUpdated
+		switch {
+		case prev.Status != StatusClosed && bead.Status == StatusClosed:
+			changeType = ChangeClosed
+		case prev.Status == StatusClosed && bead.Status != StatusClosed:
+			changeType = ChangeReopened
+		}
+		changes = append(changes, &Change{Type: changeType, ID: bead.ID, Bead: bead, Previous: prev, Fields: fields})
+	}
+
+	before := dependencyKeys(prev)
+	after := dependencyKeys(bead)
+	for key, dep := range after {
+		if _, ok := before[key]; !ok {
+			changes = append(changes, &Change{Type: ChangeDependencyAdded, ID: bead.ID, Bead: bead, Previous: prev, Dependency: dep})
+		}
+	}
+	for key, dep := range before {
+		if _, ok := after[key]; !ok {
+			changes = append(changes, &Change{Type: ChangeDependencyRemoved, ID: bead.ID, Bead: bead, Previous: prev, Dependency: dep})
+		}
+	}
+	return changes
+}
+
+// dependencyKeys indexes a bead's dependencies by target and type
+func dependencyKeys(bead *Bead) map[string]*Dependency {
+	keys := make(map[string]*Dependency, len(bead.Dependencies))
+	for _, dep := range bead.Dependencies {
+		keys[dep.DependsOnID+"\x00"+string(dep.Type)] = dep
+	}
+	return keys
+}
+
+// storedField is a Bead field that is read from the data source, as opposed
+// to one computed while linking the graph
+type storedField struct {
+	name  string // JSON name
+	same  func(a, b *Bead) bool
+	value func(b *Bead) interface{}
+}
+
+// storedFields lists the Bead fields compared by ChangedFields, in struct
+// order. Fields without a JSON tag are computed, and dependencies get their
+// own change events.
+var storedFields = []storedField{
+	field("id", func(b *Bead) string { return b.ID }),
+	field("title", func(b *Bead) string { return b.Title }),
+	field("description", func(b *Bead) string { return b.Description }),
+	field("design", func(b *Bead) string { return b.Design }),
+	field("acceptance_criteria", func(b *Bead) string { return b.AcceptanceCriteria }),
+	field("notes", func(b *Bead) string { return b.Notes }),
+	field("status", func(b *Bead) Status { return b.Status }),
+	field("priority", func(b *Bead) int { return b.Priority }),
+	field("issue_type", func(b *Bead) BeadType { return b.Type }),
+	field("close_reason", func(b *Bead) string { return b.CloseReason }),
+	field("assignee", func(b *Bead) string { return b.Assignee }),
+	optionalField("estimated_minutes", func(b *Bead) *int { return b.EstimatedMinutes }),
+	timeField("created_at", func(b *Bead) time.Time { return b.CreatedAt }),
+	field("created_by", func(b *Bead) string { return b.CreatedBy }),
+	timeField("updated_at", func(b *Bead) time.Time { return b.UpdatedAt }),
+	optionalTimeField("closed_at", func(b *Bead) *time.Time { return b.ClosedAt }),
+	optionalTimeField("due_at", func(b *Bead) *time.Time { return b.DueAt }),
+	optionalTimeField("defer_until", func(b *Bead) *time.Time { return b.DeferUntil }),
+	optionalField("external_ref", func(b *Bead) *string { return b.ExternalRef }),
+	{
+		name:  "labels",
+		same:  func(a, b *Bead) bool { return slices.Equal(a.Labels, b.Labels) },
+		value: func(b *Bead) interface{} { return b.Labels },
+	},
+	{
+		name: "comments",
+		same: func(a, b *Bead) bool {
+			return slices.EqualFunc(a.Comments, b.Comments, func(x, y *Comment) bool {
+				return x.ID == y.ID && x.IssueID == y.IssueID && x.Author == y.Author &&
+					x.Text == y.Text && x.CreatedAt.Equal(y.CreatedAt)
+			})
+		},
+		value: func(b *Bead) interface{} { return b.Comments },
+	},
+	optionalTimeField("deleted_at", func(b *Bead) *time.Time { return b.DeletedAt }),
+	field("deleted_by", func(b *Bead) string { return b.DeletedBy }),
+	field("delete_reason", func(b *Bead) string { return b.DeleteReason }),
+	field("conflicted", func(b *Bead) bool { return b.Conflicted }),
+	field("project", func(b *Bead) string { return b.Project }),
+}
+
+// field compares a scalar field
+func field[T comparable](name string, get func(*Bead) T) storedField {
+	return storedField{
+		name:  name,
+		same:  func(a, b *Bead) bool { return get(a) == get(b) },
+		value: func(b *Bead) interface{} { return get(b) },
+	}
+}
+
+// optionalField compares the values behind a pointer field; nil only equals nil
+func optionalField[T comparable](name string, get func(*Bead) *T) storedField {
+	return storedField{
+		name: name,
+		same: func(a, b *Bead) bool {
+			x, y := get(a), get(b)
+			return x == y || x != nil && y != nil && *x == *y
+		},
+		value: func(b *Bead) interface{} { return get(b) },
+	}
+}
+
+// timeField compares timestamps as instants, whatever their location
+func timeField(name string, get func(*Bead) time.Time) storedField {
+	return storedField{
+		name:  name,
+		same:  func(a, b *Bead) bool { return get(a).Equal(get(b)) },
+		value: func(b *Bead) interface{} { return get(b) },
+	}
+}
+
+// optionalTimeField compares optional timestamps as instants
+func optionalTimeField(name string, get func(*Bead) *time.Time) storedField {
+	return storedField{
+		name: name,
+		same: func(a, b *Bead) bool {
+			x, y := get(a), get(b)
+			return x == y || x != nil && y != nil && x.Equal(*y)
+		},
+		value: func(b *Bead) interface{} { return get(b) },
+	}
+}
+
+// ChangedFields returns the new value of every stored field that differs
+// between two versions of a bead, keyed by JSON name. Timestamps are equal
+// if they are the same instant, and empty lists equal missing ones.
+func ChangedFields(prev, bead *Bead) map[string]interface{} {
+	var fields map[string]interface{}
+	for _, field := range storedFields {
+		if field.same(prev, bead) {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]interface{})
+		}
+		fields[field.name] = field.value(bead)
+	}
+	return fields
+}
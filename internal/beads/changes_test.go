@@ -0,0 +1,97 @@
+package beads
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoredFieldsCoverBead(t *testing.T) {
+	var want []string
+	beadType := reflect.TypeOf(Bead{})
+	for i := 0; i < beadType.NumField(); i++ {
+		tag, ok := beadType.Field(i).Tag.Lookup("json")
+		name, _, _ := strings.Cut(tag, ",")
+		if ok && name != "-" && name != "dependencies" {
+			want = append(want, name)
+		}
+	}
+
+	var got []string
+	for _, field := range storedFields {
+		got = append(got, field.name)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("storedFields = %v\nwant %v", got, want)
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	local := now.In(time.FixedZone("CET", 3600))
+	five, six := 5, 6
+
+	tests := []struct {
+		name   string
+		edit   func(b *Bead)
+		fields []string
+	}{
+		{"no change", func(b *Bead) {}, nil},
+		{"title", func(b *Bead) { b.Title = "new" }, []string{"title"}},
+		{"same instant elsewhere", func(b *Bead) { b.UpdatedAt = local }, nil},
+		{"optional time set", func(b *Bead) { b.ClosedAt = &now }, []string{"closed_at"}},
+		{"optional time same instant", func(b *Bead) { b.DueAt = &local }, nil},
+		{"pointer value", func(b *Bead) { b.EstimatedMinutes = &six }, []string{"estimated_minutes"}},
+		{"pointer same value", func(b *Bead) { other := 5; b.EstimatedMinutes = &other }, nil},
+		{"empty labels", func(b *Bead) { b.Labels = []string{} }, nil},
+		{"labels", func(b *Bead) { b.Labels = []string{"b"} }, []string{"labels"}},
+		{"status and priority", func(b *Bead) { b.Status = StatusClosed; b.Priority = 1 }, []string{"priority", "status"}},
+		{"computed fields ignored", func(b *Bead) { b.ParentID = "bd-9"; b.BlockerIDs = []string{"bd-8"} }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := &Bead{ID: "bd-1", Title: "old", Status: StatusOpen, Priority: 2, UpdatedAt: now, DueAt: &now, EstimatedMinutes: &five}
+			bead := *prev
+			tt.edit(&bead)
+
+			var got []string
+			for name := range ChangedFields(prev, &bead) {
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.fields, ",") {
+				t.Errorf("changed = %v, want %v", got, tt.fields)
+			}
+		})
+	}
+}
+
+func TestDiffSkipsUnchangedRecords(t *testing.T) {
+	path := writeJSONL(t, record("bd-1", "one", 1), record("bd-2", "two", 1))
+	graph, err := BuildGraph(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := graph.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	if changes := graph.Snapshot().Changes; len(changes) != 0 {
+		t.Errorf("rebuilding an unchanged file gave %d changes", len(changes))
+	}
+
+	// Appending only compares the touched bead
+	appendJSONL(t, path, record("bd-2", "closed", 2))
+	graph.Source.(AppendSource).Appended()
+	if err := graph.LoadAppended(); err != nil {
+		t.Fatal(err)
+	}
+	changes := graph.Snapshot().Changes
+	if len(changes) != 1 || changes[0].ID != "bd-2" || changes[0].Type != ChangeUpdated {
+		t.Fatalf("changes = %+v", changes)
+	}
+	if len(changes[0].Fields) != 2 || changes[0].Fields["title"] != "closed" {
+		t.Errorf("fields = %v", changes[0].Fields)
+	}
+}
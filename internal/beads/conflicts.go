@@ -0,0 +1,134 @@
+package beads
+
+import (
+	"sort"
+)
+
+// RecordVersion is one record of a bead as it appeared in the data file
+type RecordVersion struct {
+	Line int   `json:"line,omitempty"`
+	Bead *Bead `json:"bead"`
+}
+
+// Conflict describes a bead ID that had more than one competing record.
+// The record with the newest updated_at wins; on a tie the one later in the file wins.
+// A later record with a newer updated_at is an ordinary edit, not a conflict.
+type Conflict struct {
+	ID     string           `json:"id"`
+	Winner *RecordVersion   `json:"winner"`
+	Losers []*RecordVersion `json:"losers"`
+}
+
+func newRecordVersion(bead *Bead) *RecordVersion {
+	return &RecordVersion{Line: bead.SourceLine, Bead: bead}
+}
+
+// maxConflictLosers caps the losing versions kept per conflict; older ones
+// are dropped
+const maxConflictLosers = 10
+
+// supersedes reports whether candidate should replace current.
+// Candidate is assumed to come later in the file, so it wins ties.
+func supersedes(candidate, current *Bead) bool {
+	return !candidate.UpdatedAt.Before(current.UpdatedAt)
+}
+
+// competes reports whether a later record of a bead really competes with an
+// earlier one, rather than being an ordinary edit of it: it isn't newer, or
+// either of them came from inside a git merge conflict hunk
+func competes(earlier, later *Bead) bool {
+	return earlier.Conflicted || later.Conflicted || !later.UpdatedAt.After(earlier.UpdatedAt)
+}
+
+// resolveRecord returns the winner between current, the winning record for
+// an ID so far, and record, which comes after it in the file. Competing
+// records are noted in conflicts. An ordinary edit clears the ID's conflict,
+// since it supersedes every version that competed. A record identical to
+// current, such as an unterminated last line read again once its newline is
+// written, is the same record and never conflicts with it.
+func resolveRecord(conflicts map[string]*Conflict, current, record *Bead) *Bead {
+	if sameRecord(current, record) {
+		return record
+	}
+	if !competes(current, record) {
+		delete(conflicts, record.ID)
+		return record
+	}
+	if supersedes(record, current) {
+		recordConflict(conflicts, record, current)
+		return record
+	}
+	recordConflict(conflicts, current, record)
+	return current
+}
+
+// recordConflict notes that loser competed with winner for the same ID,
+// replacing rather than modifying any existing entry, so the map may share
+// entries with an earlier snapshot
+func recordConflict(conflicts map[string]*Conflict, winner, loser *Bead) {
+	next := &Conflict{ID: winner.ID, Winner: newRecordVersion(winner)}
+	if prev := conflicts[winner.ID]; prev != nil {
+		next.Losers = append(next.Losers, prev.Losers...)
+	}
+	next.Losers = append(next.Losers, newRecordVersion(loser))
+	if len(next.Losers) > maxConflictLosers {
+		next.Losers = next.Losers[len(next.Losers)-maxConflictLosers:]
+	}
+	conflicts[winner.ID] = next
+}
+
+// fileOrder returns all records of a parse result, tombstones included,
+// in the order they appeared in the file
+func fileOrder(result *ParseResult) []*Bead {
+	records := make([]*Bead, 0, len(result.Beads)+len(result.Tombstones))
+	records = append(records, result.Beads...)
+	records = append(records, result.Tombstones...)
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].SourceLine < records[j].SourceLine
+	})
+	return records
+}
+
+// resolveDuplicates picks one winning record per ID and reports every ID that
+// had competing records. Winners that are tombstones are dropped from the result.
+func resolveDuplicates(result *ParseResult) ([]*Bead, map[string]*Conflict) {
+	winners := make(map[string]*Bead)
+	conflicts := make(map[string]*Conflict)
+	var order []string
+
+	for _, record := range fileOrder(result) {
+		current, ok := winners[record.ID]
+		if !ok {
+			winners[record.ID] = record
+			order = append(order, record.ID)
+			continue
+		}
+
+		winners[record.ID] = resolveRecord(conflicts, current, record)
+	}
+
+	beads := make([]*Bead, 0, len(order))
+	for _, id := range order {
+		if winner := winners[id]; !winner.IsTombstone() {
+			beads = append(beads, winner)
+		}
+	}
+	return beads, conflicts
+}
+
+// GetConflicts returns every bead ID that had competing records, sorted by ID
+func (s *Snapshot) GetConflicts() []*Conflict {
+	conflicts := make([]*Conflict, 0, len(s.Conflicts))
+	for _, conflict := range s.Conflicts {
+		conflicts = append(conflicts, conflict)
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].ID < conflicts[j].ID
+	})
+	return conflicts
+}
+
+// GetMergeConflicts returns the unresolved git conflict hunks in the data file
+func (s *Snapshot) GetMergeConflicts() []*MergeConflict {
+	return s.MergeConflicts
+}
@@ -0,0 +1,145 @@
+package beads
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// record returns a JSONL line for a bead updated on the given day of
+// January 2026
+func record(id, title string, day int) string {
+	return fmt.Sprintf(`{"id":%q,"title":%q,"status":"open","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-%02dT00:00:00Z"}`+"\n", id, title, day)
+}
+
+func writeJSONL(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "issues.jsonl")
+	content := ""
+	for _, line := range lines {
+		content += line
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func appendJSONL(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	for _, line := range lines {
+		if _, err := file.WriteString(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResolveDuplicates(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     []string
+		title     string
+		conflict  bool
+		numLosers int
+	}{
+		{"newer edit", []string{record("bd-1", "old", 1), record("bd-1", "new", 2)}, "new", false, 0},
+		{"tie goes to the later record", []string{record("bd-1", "first", 1), record("bd-1", "second", 1)}, "second", true, 1},
+		{"stale record loses", []string{record("bd-1", "new", 2), record("bd-1", "stale", 1)}, "new", true, 1},
+		{"edit clears earlier conflict", []string{record("bd-1", "a", 1), record("bd-1", "b", 1), record("bd-1", "c", 2)}, "c", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph, err := BuildGraph(writeJSONL(t, tt.lines...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			snap := graph.Snapshot()
+			if got := snap.Beads["bd-1"].Title; got != tt.title {
+				t.Errorf("winner = %q, want %q", got, tt.title)
+			}
+			conflict := snap.Conflicts["bd-1"]
+			if (conflict != nil) != tt.conflict {
+				t.Fatalf("conflict = %v, want %v", conflict != nil, tt.conflict)
+			}
+			if conflict != nil && len(conflict.Losers) != tt.numLosers {
+				t.Errorf("losers = %d, want %d", len(conflict.Losers), tt.numLosers)
+			}
+		})
+	}
+}
+
+func TestAppendedEditsAreNotConflicts(t *testing.T) {
+	path := writeJSONL(t, record("bd-1", "v1", 1))
+	graph, err := BuildGraph(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := graph.Source.(AppendSource)
+
+	for day := 2; day <= 20; day++ {
+		appendJSONL(t, path, record("bd-1", fmt.Sprintf("v%d", day), day))
+		if !src.Appended() {
+			t.Fatal("append not detected")
+		}
+		if err := graph.LoadAppended(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snap := graph.Snapshot()
+	if len(snap.Conflicts) != 0 {
+		t.Fatalf("ordinary edits recorded as %d conflict(s)", len(snap.Conflicts))
+	}
+	if got := snap.Beads["bd-1"].Title; got != "v20" {
+		t.Errorf("title = %q, want v20", got)
+	}
+
+	// Stale writes compete; only the newest losers are kept
+	for i := 0; i < maxConflictLosers+5; i++ {
+		appendJSONL(t, path, record("bd-1", fmt.Sprintf("stale%d", i), 1))
+	}
+	src.Appended()
+	if err := graph.LoadAppended(); err != nil {
+		t.Fatal(err)
+	}
+	conflict := graph.Snapshot().Conflicts["bd-1"]
+	if conflict == nil {
+		t.Fatal("stale writes not recorded as a conflict")
+	}
+	if len(conflict.Losers) != maxConflictLosers {
+		t.Errorf("losers = %d, want %d", len(conflict.Losers), maxConflictLosers)
+	}
+	if last := conflict.Losers[len(conflict.Losers)-1].Bead.Title; last != fmt.Sprintf("stale%d", maxConflictLosers+4) {
+		t.Errorf("newest loser = %q", last)
+	}
+}
+
+func TestUnterminatedLineReadAgainIsNotAConflict(t *testing.T) {
+	line := record("a-2", "v1", 1)
+	path := writeJSONL(t, record("a-1", "v1", 1), line[:len(line)-1])
+	graph, err := BuildGraph(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := graph.Source.(AppendSource)
+
+	appendJSONL(t, path, "\n")
+	if !src.Appended() {
+		t.Fatal("append not detected")
+	}
+	if err := graph.LoadAppended(); err != nil {
+		t.Fatal(err)
+	}
+	snap := graph.Snapshot()
+	if conflict := snap.Conflicts["a-2"]; conflict != nil {
+		t.Fatalf("a-2 conflicts with itself: %d loser(s)", len(conflict.Losers))
+	}
+	if got := snap.Beads["a-2"].Title; got != "v1" {
+		t.Errorf("title = %q, want v1", got)
+	}
+}
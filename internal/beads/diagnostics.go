@@ -0,0 +1,91 @@
+package beads
+
+import (
+	"sort"
+)
+
+// DanglingDependency is a dependency whose depends_on_id matches no bead
+type DanglingDependency struct {
+	IssueID     string         `json:"issueId"`
+	DependsOnID string         `json:"dependsOnId"`
+	Type        DependencyType `json:"type"`
+}
+
+// OrphanedChild is a bead whose parent ID matches no bead
+type OrphanedChild struct {
+	ID       string `json:"id"`
+	ParentID string `json:"parentId"`
+}
+
+// UnknownValue is a bead with a status or type bd doesn't define
+type UnknownValue struct {
+	ID    string `json:"id"`
+	Value string `json:"value"`
+}
+
+// Diagnostics summarizes data-quality problems in the loaded beads
+type Diagnostics struct {
+	ParseErrors          []*ParseError         `json:"parseErrors"`
+	DanglingDependencies []*DanglingDependency `json:"danglingDependencies"`
+	OrphanedChildren     []*OrphanedChild      `json:"orphanedChildren"`
+	UnknownStatuses      []*UnknownValue       `json:"unknownStatuses"`
+	UnknownTypes         []*UnknownValue       `json:"unknownTypes"`
+}
+
+// Total returns the number of problems found
+func (d *Diagnostics) Total() int {
+	return len(d.ParseErrors) + len(d.DanglingDependencies) + len(d.OrphanedChildren) +
+		len(d.UnknownStatuses) + len(d.UnknownTypes)
+}
+
+// GetDiagnostics checks the graph for parse errors and broken references
+func (s *Snapshot) GetDiagnostics() *Diagnostics {
+	diag := &Diagnostics{
+		ParseErrors:          make([]*ParseError, len(s.Errors)),
+		DanglingDependencies: make([]*DanglingDependency, 0),
+		OrphanedChildren:     make([]*OrphanedChild, 0),
+		UnknownStatuses:      make([]*UnknownValue, 0),
+		UnknownTypes:         make([]*UnknownValue, 0),
+	}
+	copy(diag.ParseErrors, s.Errors)
+
+	// Walk beads in ID order so the report is stable between requests
+	ids := make([]string, 0, len(s.Beads))
+	for id := range s.Beads {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		bead := s.Beads[id]
+
+		for _, dep := range bead.Dependencies {
+			if dep.DependsOnID == "" {
+				continue
+			}
+			if _, ok := s.Beads[dep.DependsOnID]; !ok {
+				diag.DanglingDependencies = append(diag.DanglingDependencies, &DanglingDependency{
+					IssueID:     bead.ID,
+					DependsOnID: dep.DependsOnID,
+					Type:        dep.Type,
+				})
+			}
+		}
+
+		if bead.ParentID != "" && bead.Parent == nil {
+			diag.OrphanedChildren = append(diag.OrphanedChildren, &OrphanedChild{
+				ID:       bead.ID,
+				ParentID: bead.ParentID,
+			})
+		}
+
+		if !bead.Status.IsKnown() {
+			diag.UnknownStatuses = append(diag.UnknownStatuses, &UnknownValue{ID: bead.ID, Value: string(bead.Status)})
+		}
+		if !bead.Type.IsKnown() {
+			diag.UnknownTypes = append(diag.UnknownTypes, &UnknownValue{ID: bead.ID, Value: string(bead.Type)})
+		}
+	}
+
+	return diag
+}
@@ -4,12 +4,16 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// BeadsGraph represents the in-memory graph of all beads
-type BeadsGraph struct {
-	mu sync.RWMutex
+// Snapshot is an immutable view of the graph at one generation.
+// Nothing reachable from a published snapshot is ever modified, so a request
+// can pin one snapshot and read it without locks while reloads carry on.
+type Snapshot struct {
+	// Generation increases by one every time a new snapshot is published
+	Generation uint64
 
 	// Primary storage
 	Beads     map[string]*Bead // ID -> Bead lookup
@@ -21,129 +25,218 @@ type BeadsGraph struct {
 	ByPriority map[int][]*Bead
 	ByLabel    map[string][]*Bead
 
+	// Bead IDs that had more than one competing record
+	Conflicts map[string]*Conflict
+
+	// Unresolved git conflict hunks in the data file
+	MergeConflicts []*MergeConflict
+
+	// Errors from the latest load
+	Errors []*ParseError
+
+	// Changes since the previous generation; nil for the first one
+	Changes []*Change
+
+	// Missing IDs -> beads whose parent or blockers refer to them, so an
+	// appended bead can be linked to its referrers without a full pass
+	dangling map[string][]string
+
+	// IDs whose records were applied on top of the previous snapshot, for a
+	// snapshot merged from appended records; nil for full builds
+	touched map[string]bool
+
 	// Metadata
 	LastUpdated time.Time
 	FileSize    int64
-	FilePath    string
 }
 
-// NewGraph creates a new empty BeadsGraph
-func NewGraph() *BeadsGraph {
-	return &BeadsGraph{
+// newSnapshot creates an empty, unpublished snapshot
+func newSnapshot() *Snapshot {
+	return &Snapshot{
 		Beads:      make(map[string]*Bead),
 		RootBeads:  make([]*Bead, 0),
 		ByStatus:   make(map[Status][]*Bead),
 		ByType:     make(map[BeadType][]*Bead),
 		ByPriority: make(map[int][]*Bead),
 		ByLabel:    make(map[string][]*Bead),
+		Conflicts:  make(map[string]*Conflict),
+		dangling:   make(map[string][]string),
 	}
 }
 
-// BuildGraph parses a JSONL file and constructs the graph
-func BuildGraph(jsonlPath string) (*BeadsGraph, error) {
-	result, err := ParseJSONL(jsonlPath)
-	if err != nil {
-		return nil, err
-	}
+// BeadsGraph represents the in-memory graph of all beads.
+// Readers call Snapshot; reloads build a new snapshot off to the side and
+// publish it atomically.
+type BeadsGraph struct {
+	mu sync.Mutex // Serializes writers
 
-	graph := NewGraph()
-	graph.FilePath = jsonlPath
-	graph.FileSize = result.FileSize
-	graph.LastUpdated = time.Now()
+	current  atomic.Pointer[Snapshot]
+	degraded atomic.Pointer[DegradedError] // Set while reloads are being rejected
 
-	// Step 1: Add all beads to the map
-	for _, bead := range result.Beads {
-		graph.Beads[bead.ID] = bead
+	reloads        atomic.Uint64 // Counted for ReloadStats
+	reloadFailures atomic.Uint64
+	reloadTime     atomic.Int64 // Nanoseconds
+
+	Source DataSource   // Where beads are loaded from on rebuild
+	Policy ReloadPolicy // When to reject a reload and keep the previous data
+}
+
+// NewGraph creates a new empty BeadsGraph
+func NewGraph() *BeadsGraph {
+	g := &BeadsGraph{
+		Policy: DefaultReloadPolicy(),
 	}
+	g.current.Store(newSnapshot())
+	return g
+}
 
-	// Step 2: Resolve parent/child relationships
-	for _, bead := range graph.Beads {
-		if bead.ParentID != "" {
-			if parent, ok := graph.Beads[bead.ParentID]; ok {
-				bead.Parent = parent
-				parent.Children = append(parent.Children, bead)
-			}
-		} else {
-			graph.RootBeads = append(graph.RootBeads, bead)
-		}
+// Snapshot returns the current snapshot. Handlers should call this once per
+// request and read everything from the result.
+func (g *BeadsGraph) Snapshot() *Snapshot {
+	return g.current.Load()
+}
+
+// Generation returns the generation of the current snapshot. It only ever
+// increases, so clients can use it to tell whether they have the latest data.
+func (g *BeadsGraph) Generation() uint64 {
+	return g.current.Load().Generation
+}
+
+// publish makes next the current snapshot under the next generation and
+// records what changed since the previous one. Callers must hold the write lock.
+func (g *BeadsGraph) publish(next *Snapshot) {
+	prev := g.current.Load()
+	next.Generation = prev.Generation + 1
+	if prev.Generation > 0 {
+		next.Changes = Diff(prev, next)
 	}
+	g.current.Store(next)
+}
 
-	// Step 3: Resolve blocker/blocked relationships
-	for _, bead := range graph.Beads {
-		for _, blockerID := range bead.BlockerIDs {
-			if blocker, ok := graph.Beads[blockerID]; ok {
-				bead.Blockers = append(bead.Blockers, blocker)
-				blocker.Blocked = append(blocker.Blocked, bead)
-				blocker.BlockedIDs = append(blocker.BlockedIDs, bead.ID)
-			}
-		}
+// BuildGraph parses a JSONL file and constructs the graph
+func BuildGraph(jsonlPath string) (*BeadsGraph, error) {
+	return BuildGraphFromSource(NewJSONLSource(jsonlPath))
+}
+
+// BuildGraphFromSource loads beads from a DataSource and constructs the graph
+func BuildGraphFromSource(src DataSource) (*BeadsGraph, error) {
+	result, err := src.Load()
+	if err != nil {
+		return nil, err
 	}
 
-	// Step 4: Build indices
-	graph.rebuildIndices()
+	graph := NewGraph()
+	graph.Source = src
+	graph.publish(buildSnapshot(result))
 
 	return graph, nil
 }
 
-// Rebuild reconstructs the graph from the JSONL file
+// Rebuild reconstructs the graph from its data source.
+// The new snapshot is built off to the side and only published if it passes
+// the reload policy; otherwise the previous snapshot keeps serving and a
+// *DegradedError is returned.
 func (g *BeadsGraph) Rebuild() error {
+	return g.rebuild(false)
+}
+
+// ForceRebuild reconstructs the graph even if the result fails the reload
+// policy. Used once a suspicious file has stayed the same across retries.
+func (g *BeadsGraph) ForceRebuild() error {
+	return g.rebuild(true)
+}
+
+func (g *BeadsGraph) rebuild(force bool) (err error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	defer g.recordReload(time.Now(), &err)
 
-	result, err := ParseJSONL(g.FilePath)
+	result, err := g.Source.Load()
 	if err != nil {
-		return err
+		return g.degrade(err.Error())
 	}
 
-	// Clear existing data
-	g.Beads = make(map[string]*Bead)
-	g.RootBeads = make([]*Bead, 0)
-	g.FileSize = result.FileSize
-	g.LastUpdated = time.Now()
+	next := buildSnapshot(result)
 
-	// Rebuild (same as BuildGraph but in-place)
-	for _, bead := range result.Beads {
-		g.Beads[bead.ID] = bead
+	if !force {
+		if reason := g.Policy.check(len(g.Snapshot().Beads), next, result); reason != "" {
+			return g.degrade(reason)
+		}
 	}
 
-	for _, bead := range g.Beads {
+	g.publish(next)
+	g.degraded.Store(nil)
+	return nil
+}
+
+// buildSnapshot constructs an unpublished snapshot from freshly parsed beads
+func buildSnapshot(result *ParseResult) *Snapshot {
+	snap := newSnapshot()
+	snap.FileSize = result.FileSize
+	snap.LastUpdated = time.Now()
+
+	// Resolve duplicate IDs
+	beads, conflicts := resolveDuplicates(result)
+	snap.Conflicts = conflicts
+	snap.MergeConflicts = result.MergeConflicts
+	snap.Errors = result.Errors
+
+	snap.link(beads)
+	return snap
+}
+
+// link adds beads to an unpublished snapshot and resolves all relationships.
+// The beads must not belong to any other snapshot.
+func (s *Snapshot) link(beads []*Bead) {
+	// Step 1: Add all beads to the map
+	for _, bead := range beads {
+		s.Beads[bead.ID] = bead
+	}
+
+	// Step 2: Resolve parent/child relationships
+	for _, bead := range s.Beads {
 		if bead.ParentID != "" {
-			if parent, ok := g.Beads[bead.ParentID]; ok {
+			if parent, ok := s.Beads[bead.ParentID]; ok {
 				bead.Parent = parent
 				parent.Children = append(parent.Children, bead)
+			} else {
+				s.dangling[bead.ParentID] = append(s.dangling[bead.ParentID], bead.ID)
 			}
 		} else {
-			g.RootBeads = append(g.RootBeads, bead)
+			s.RootBeads = append(s.RootBeads, bead)
 		}
 	}
 
-	for _, bead := range g.Beads {
+	// Step 3: Resolve blocker/blocked relationships
+	for _, bead := range s.Beads {
 		for _, blockerID := range bead.BlockerIDs {
-			if blocker, ok := g.Beads[blockerID]; ok {
+			if blocker, ok := s.Beads[blockerID]; ok {
 				bead.Blockers = append(bead.Blockers, blocker)
 				blocker.Blocked = append(blocker.Blocked, bead)
 				blocker.BlockedIDs = append(blocker.BlockedIDs, bead.ID)
+			} else {
+				s.dangling[blockerID] = append(s.dangling[blockerID], bead.ID)
 			}
 		}
 	}
 
-	g.rebuildIndices()
-	return nil
+	// Step 4: Build indices
+	s.rebuildIndices()
 }
 
-func (g *BeadsGraph) rebuildIndices() {
-	g.ByStatus = make(map[Status][]*Bead)
-	g.ByType = make(map[BeadType][]*Bead)
-	g.ByPriority = make(map[int][]*Bead)
-	g.ByLabel = make(map[string][]*Bead)
+func (s *Snapshot) rebuildIndices() {
+	s.ByStatus = make(map[Status][]*Bead)
+	s.ByType = make(map[BeadType][]*Bead)
+	s.ByPriority = make(map[int][]*Bead)
+	s.ByLabel = make(map[string][]*Bead)
 
-	for _, bead := range g.Beads {
-		g.ByStatus[bead.Status] = append(g.ByStatus[bead.Status], bead)
-		g.ByType[bead.Type] = append(g.ByType[bead.Type], bead)
-		g.ByPriority[bead.Priority] = append(g.ByPriority[bead.Priority], bead)
+	for _, bead := range s.Beads {
+		s.ByStatus[bead.Status] = append(s.ByStatus[bead.Status], bead)
+		s.ByType[bead.Type] = append(s.ByType[bead.Type], bead)
+		s.ByPriority[bead.Priority] = append(s.ByPriority[bead.Priority], bead)
 
 		for _, label := range bead.Labels {
-			g.ByLabel[label] = append(g.ByLabel[label], bead)
+			s.ByLabel[label] = append(s.ByLabel[label], bead)
 		}
 	}
 }
@@ -166,13 +259,10 @@ type Velocity struct {
 	Closed7d  int `json:"closed_7d"`
 }
 
-// GetStats returns current statistics
-func (g *BeadsGraph) GetStats() *Stats {
-	g.mu.RLock()
-	defer g.mu.RUnlock()
-
+// GetStats returns statistics for this snapshot
+func (s *Snapshot) GetStats() *Stats {
 	stats := &Stats{
-		Total:      len(g.Beads),
+		Total:      len(s.Beads),
 		ByStatus:   make(map[string]int),
 		ByType:     make(map[string]int),
 		ByPriority: make(map[string]int),
@@ -182,7 +272,7 @@ func (g *BeadsGraph) GetStats() *Stats {
 	sevenDaysAgo := time.Now().AddDate(0, 0, -7)
 	staleDays := time.Now().AddDate(0, 0, -7)
 
-	for _, bead := range g.Beads {
+	for _, bead := range s.Beads {
 		// By status
 		stats.ByStatus[string(bead.Status)]++
 
@@ -252,21 +342,28 @@ type Filter struct {
 	Type     []BeadType
 	Priority []int
 	Labels   []string
+	Assignee []string
+	Epic     string // Only beads below this epic in the parent hierarchy
+	IDs      []string
 	Search   string
 	Ready    bool
 	Limit    int
 	Offset   int
 }
 
-// GetBeads returns filtered list of beads
-func (g *BeadsGraph) GetBeads(filter *Filter) []*Bead {
-	g.mu.RLock()
-	defer g.mu.RUnlock()
+// Matches reports whether a bead passes the filter, ignoring pagination.
+// A nil filter matches every bead. beads is the map of the snapshot the bead
+// belongs to, for the epic filter.
+func (f *Filter) Matches(bead *Bead, beads map[string]*Bead) bool {
+	return matchesFilter(bead, f, beads)
+}
 
+// GetBeads returns filtered list of beads
+func (s *Snapshot) GetBeads(filter *Filter) []*Bead {
 	var results []*Bead
 
-	for _, bead := range g.Beads {
-		if matchesFilter(bead, filter) {
+	for _, bead := range s.Beads {
+		if matchesFilter(bead, filter, s.Beads) {
 			results = append(results, bead)
 		}
 	}
@@ -290,7 +387,7 @@ func (g *BeadsGraph) GetBeads(filter *Filter) []*Bead {
 	return results
 }
 
-func matchesFilter(bead *Bead, filter *Filter) bool {
+func matchesFilter(bead *Bead, filter *Filter, beads map[string]*Bead) bool {
 	if filter == nil {
 		return true
 	}
@@ -353,6 +450,21 @@ func matchesFilter(bead *Bead, filter *Filter) bool {
 		}
 	}
 
+	// Assignee filter
+	if len(filter.Assignee) > 0 && !containsString(filter.Assignee, bead.Assignee) {
+		return false
+	}
+
+	// Epic filter
+	if filter.Epic != "" && !bead.IsDescendantOf(filter.Epic, beads) {
+		return false
+	}
+
+	// ID filter
+	if len(filter.IDs) > 0 && !containsString(filter.IDs, bead.ID) {
+		return false
+	}
+
 	// Search filter (case-insensitive title/description search)
 	if filter.Search != "" {
 		search := strings.ToLower(filter.Search)
@@ -376,10 +488,8 @@ func matchesFilter(bead *Bead, filter *Filter) bool {
 }
 
 // GetBead returns a single bead by ID
-func (g *BeadsGraph) GetBead(id string) *Bead {
-	g.mu.RLock()
-	defer g.mu.RUnlock()
-	return g.Beads[id]
+func (s *Snapshot) GetBead(id string) *Bead {
+	return s.Beads[id]
 }
 
 // GetEpics returns all epics with their progress
@@ -389,13 +499,10 @@ type EpicProgress struct {
 	ClosedChildren int `json:"closedChildren"`
 }
 
-func (g *BeadsGraph) GetEpics() []*EpicProgress {
-	g.mu.RLock()
-	defer g.mu.RUnlock()
-
+func (s *Snapshot) GetEpics() []*EpicProgress {
 	var epics []*EpicProgress
 
-	for _, bead := range g.Beads {
+	for _, bead := range s.Beads {
 		if bead.Type == TypeEpic {
 			progress := &EpicProgress{
 				Bead:           bead,
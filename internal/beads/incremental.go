@@ -0,0 +1,305 @@
+package beads
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"time"
+)
+
+// LoadAppended parses only the records appended to the data source since the
+// last load and publishes a new snapshot with them merged in by ID, if any
+// whole lines were added. Published snapshots are never modified, so the
+// appended beads and their direct neighbours are re-linked on shallow copies;
+// every other bead is shared with the previous snapshot.
+//
+// The appended lines must pass the reload policy, like a full load; if they
+// don't, a *DegradedError is returned and the previous snapshot keeps serving.
+func (g *BeadsGraph) LoadAppended() (err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	defer g.recordReload(time.Now(), &err)
+
+	src, ok := g.Source.(AppendSource)
+	if !ok {
+		return fmt.Errorf("%s does not support incremental loading", g.Source.Describe())
+	}
+
+	result, err := src.LoadAppended()
+	if err != nil {
+		return err
+	}
+	// Only part of a line was written so far
+	if result.Lines == 0 {
+		return nil
+	}
+
+	prev := g.Snapshot()
+	next := prev.merge(result)
+	if reason := g.Policy.check(len(prev.Beads), next, result); reason != "" {
+		return g.degrade(reason)
+	}
+
+	g.publish(next)
+	g.degraded.Store(nil)
+	return nil
+}
+
+// merge returns a new snapshot with parsed records applied on top of s in
+// file order. A record only replaces the existing bead if its updated_at is
+// not older.
+func (s *Snapshot) merge(result *ParseResult) *Snapshot {
+	next := newSnapshot()
+	next.Beads = maps.Clone(s.Beads)
+	next.Conflicts = maps.Clone(s.Conflicts)
+	// Full slice expressions force a copy so s's backing arrays stay untouched
+	next.MergeConflicts = append(s.MergeConflicts[:len(s.MergeConflicts):len(s.MergeConflicts)], result.MergeConflicts...)
+
+	// Errors past the previously loaded lines were for a line still being
+	// written, which result has read again now that it's complete
+	next.Errors = make([]*ParseError, 0, len(s.Errors)+len(result.Errors))
+	for _, parseErr := range s.Errors {
+		if parseErr.Line <= result.PriorLines {
+			next.Errors = append(next.Errors, parseErr)
+		}
+	}
+	next.Errors = append(next.Errors, result.Errors...)
+	next.FileSize = result.FileSize
+	next.LastUpdated = time.Now()
+
+	// IDs whose bead was added, replaced or deleted
+	touched := make(map[string]bool)
+	for _, record := range fileOrder(result) {
+		if old, exists := next.Beads[record.ID]; exists && resolveRecord(next.Conflicts, old, record) == old {
+			continue
+		}
+		touched[record.ID] = true
+
+		if record.IsTombstone() {
+			delete(next.Beads, record.ID)
+			continue
+		}
+		next.Beads[record.ID] = record
+	}
+
+	next.touched = touched
+	next.relink(s, touched)
+	return next
+}
+
+// relink resolves the relationships and indices of a snapshot cloned from
+// prev after the beads in touched were added, replaced or deleted. Only the
+// touched beads and their direct neighbours, before and after, are copied and
+// re-linked; everything else is shared with prev.
+//
+// Every bead's relationships point at beads with current stored fields, but
+// a shared neighbour's own relationships may be from an earlier generation.
+// Walk further than one hop through the Beads map, not the pointers.
+func (s *Snapshot) relink(prev *Snapshot, touched map[string]bool) {
+	affected := make(map[string]bool)
+	addNeighbours := func(bead *Bead) {
+		affected[bead.ID] = true
+		for _, id := range bead.refs() {
+			affected[id] = true
+		}
+		for _, child := range bead.Children {
+			affected[child.ID] = true
+		}
+		for _, blocked := range bead.Blocked {
+			affected[blocked.ID] = true
+		}
+	}
+	for id := range touched {
+		if old := prev.Beads[id]; old != nil {
+			addNeighbours(old)
+		}
+		if bead := s.Beads[id]; bead != nil {
+			addNeighbours(bead)
+		}
+		// Beads that referred to id while it was missing
+		for _, ref := range prev.dangling[id] {
+			affected[ref] = true
+		}
+	}
+
+	// Copy the affected beads that still exist, keeping the relationships
+	// they have with beads that aren't being re-linked
+	ids := make([]string, 0, len(affected))
+	for id := range affected {
+		bead := s.Beads[id]
+		if bead == nil {
+			continue
+		}
+		ids = append(ids, id)
+
+		linked := bead.detach()
+		if old := prev.Beads[id]; old != nil {
+			for _, child := range old.Children {
+				if !affected[child.ID] {
+					linked.Children = append(linked.Children, s.Beads[child.ID])
+				}
+			}
+			for _, blocked := range old.Blocked {
+				if !affected[blocked.ID] {
+					linked.Blocked = append(linked.Blocked, s.Beads[blocked.ID])
+					linked.BlockedIDs = append(linked.BlockedIDs, blocked.ID)
+				}
+			}
+		}
+		s.Beads[id] = linked
+	}
+	sort.Strings(ids)
+
+	// Link the copies to each other and to the shared beads they point at
+	for _, id := range ids {
+		bead := s.Beads[id]
+		if parent := s.Beads[bead.ParentID]; parent != nil {
+			bead.Parent = parent
+			if affected[parent.ID] {
+				parent.Children = append(parent.Children, bead)
+			}
+		}
+		for _, blockerID := range bead.BlockerIDs {
+			if blocker := s.Beads[blockerID]; blocker != nil {
+				bead.Blockers = append(bead.Blockers, blocker)
+				if affected[blocker.ID] {
+					blocker.Blocked = append(blocker.Blocked, bead)
+					blocker.BlockedIDs = append(blocker.BlockedIDs, bead.ID)
+				}
+			}
+		}
+	}
+
+	// Touched beads that were deleted leave their entries in the indices too
+	for id := range touched {
+		affected[id] = true
+	}
+	s.reindex(prev, affected)
+}
+
+// reindex updates the root list and indices copied from prev for the beads
+// in ids, sharing every bucket that none of them was or is in
+func (s *Snapshot) reindex(prev *Snapshot, ids map[string]bool) {
+	var before, after []*Bead
+	for id := range ids {
+		if old := prev.Beads[id]; old != nil {
+			before = append(before, old)
+		}
+		if bead := s.Beads[id]; bead != nil {
+			after = append(after, bead)
+		}
+	}
+	sort.Slice(after, func(i, j int) bool { return after[i].ID < after[j].ID })
+
+	s.RootBeads = rebucket(map[bool][]*Bead{true: prev.RootBeads}, before, after, func(b *Bead) []bool {
+		return []bool{b.ParentID == ""}
+	})[true]
+	s.ByStatus = rebucket(prev.ByStatus, before, after, func(b *Bead) []Status { return []Status{b.Status} })
+	s.ByType = rebucket(prev.ByType, before, after, func(b *Bead) []BeadType { return []BeadType{b.Type} })
+	s.ByPriority = rebucket(prev.ByPriority, before, after, func(b *Bead) []int { return []int{b.Priority} })
+	s.ByLabel = rebucket(prev.ByLabel, before, after, func(b *Bead) []string { return b.Labels })
+
+	// Missing IDs that beads refer to
+	s.dangling = maps.Clone(prev.dangling)
+	keys := make(map[string]bool)
+	for _, bead := range before {
+		for _, ref := range bead.refs() {
+			keys[ref] = true
+		}
+	}
+	for id := range ids {
+		keys[id] = true
+	}
+	for key := range keys {
+		var refs []string
+		for _, ref := range prev.dangling[key] {
+			if !ids[ref] {
+				refs = append(refs, ref)
+			}
+		}
+		setDangling(s.dangling, key, refs)
+	}
+	for _, bead := range after {
+		for _, ref := range bead.refs() {
+			if s.Beads[ref] == nil {
+				s.dangling[ref] = append(s.dangling[ref][:len(s.dangling[ref]):len(s.dangling[ref])], bead.ID)
+			}
+		}
+	}
+}
+
+// setDangling replaces the beads referring to a missing ID
+func setDangling(dangling map[string][]string, id string, refs []string) {
+	if len(refs) == 0 {
+		delete(dangling, id)
+		return
+	}
+	dangling[id] = refs
+}
+
+// rebucket returns a copy of index with the previous versions of some beads
+// (before) taken out of their buckets and the current ones (after) added.
+// Buckets none of them was or is in are shared with index.
+func rebucket[K comparable](index map[K][]*Bead, before, after []*Bead, keys func(*Bead) []K) map[K][]*Bead {
+	stale := make(map[*Bead]bool, len(before))
+	changed := make(map[K][]*Bead)
+	for _, bead := range before {
+		stale[bead] = true
+		for _, key := range keys(bead) {
+			changed[key] = nil
+		}
+	}
+	for _, bead := range after {
+		for _, key := range keys(bead) {
+			changed[key] = nil
+		}
+	}
+	for key := range changed {
+		bucket := make([]*Bead, 0, len(index[key])+len(after))
+		for _, bead := range index[key] {
+			if !stale[bead] {
+				bucket = append(bucket, bead)
+			}
+		}
+		changed[key] = bucket
+	}
+	for _, bead := range after {
+		for _, key := range keys(bead) {
+			changed[key] = append(changed[key], bead)
+		}
+	}
+
+	next := maps.Clone(index)
+	if next == nil {
+		next = make(map[K][]*Bead)
+	}
+	for key, bucket := range changed {
+		if len(bucket) == 0 {
+			delete(next, key)
+			continue
+		}
+		next[key] = bucket
+	}
+	return next
+}
+
+// refs returns the IDs the bead refers to: its parent and its blockers
+func (b *Bead) refs() []string {
+	refs := b.BlockerIDs[:len(b.BlockerIDs):len(b.BlockerIDs)]
+	if b.ParentID != "" {
+		refs = append(refs, b.ParentID)
+	}
+	return refs
+}
+
+// detach returns a shallow copy of the bead with its computed relationships
+// cleared, ready to be linked into a new snapshot
+func (b *Bead) detach() *Bead {
+	clone := *b
+	clone.Parent = nil
+	clone.Children = nil
+	clone.Blockers = nil
+	clone.Blocked = nil
+	clone.BlockedIDs = nil
+	return &clone
+}
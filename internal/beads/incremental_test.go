@@ -0,0 +1,308 @@
+package beads
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// randomRecord returns a JSONL line for id with a random status, priority,
+// label and blockers drawn from ids, updated at the given step
+func randomRecord(rng *rand.Rand, id string, ids []string, step int) string {
+	statuses := []Status{StatusOpen, StatusInProgress, StatusClosed, StatusTombstone}
+	var deps []*Dependency
+	for i := rng.Intn(3); i > 0; i-- {
+		deps = append(deps, &Dependency{IssueID: id, DependsOnID: ids[rng.Intn(len(ids))], Type: "blocks"})
+	}
+	line, _ := json.Marshal(map[string]interface{}{
+		"id":           id,
+		"title":        fmt.Sprintf("%s v%d", id, step),
+		"status":       statuses[rng.Intn(len(statuses))],
+		"priority":     rng.Intn(5),
+		"labels":       []string{fmt.Sprintf("l%d", rng.Intn(3))},
+		"dependencies": deps,
+		"created_at":   "2026-01-01T00:00:00Z",
+		"updated_at":   time.Date(2026, 1, 1, 0, 0, step, 0, time.UTC),
+	})
+	return string(line) + "\n"
+}
+
+// shape describes a snapshot's relationships and indices by ID, so two
+// snapshots can be compared whatever the order of their slices
+func shape(s *Snapshot) map[string]string {
+	ids := func(beads []*Bead) string {
+		var list []string
+		for _, bead := range beads {
+			// Neighbours must carry the current stored fields
+			list = append(list, bead.ID+"="+bead.Title)
+		}
+		sort.Strings(list)
+		return strings.Join(list, ",")
+	}
+
+	out := make(map[string]string)
+	for id, bead := range s.Beads {
+		parent := ""
+		if bead.Parent != nil {
+			parent = ids([]*Bead{bead.Parent})
+		}
+		blocked := append([]string(nil), bead.BlockedIDs...)
+		sort.Strings(blocked)
+		out["bead "+id] = fmt.Sprintf("%s parent %s children %s blockers %s blocked %s %v",
+			bead.Title, parent, ids(bead.Children), ids(bead.Blockers), ids(bead.Blocked), blocked)
+	}
+	out["roots"] = ids(s.RootBeads)
+	for key, bucket := range s.ByStatus {
+		out["status "+string(key)] = ids(bucket)
+	}
+	for key, bucket := range s.ByType {
+		out["type "+string(key)] = ids(bucket)
+	}
+	for key, bucket := range s.ByPriority {
+		out[fmt.Sprint("priority ", key)] = ids(bucket)
+	}
+	for key, bucket := range s.ByLabel {
+		out["label "+key] = ids(bucket)
+	}
+	for key, refs := range s.dangling {
+		refs = append([]string(nil), refs...)
+		sort.Strings(refs)
+		out["dangling "+key] = strings.Join(refs, ",")
+	}
+	return out
+}
+
+func TestLoadAppendedMatchesRebuild(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	ids := []string{"bd-1", "bd-1.1", "bd-1.2", "bd-1.1.1", "bd-2", "bd-2.1", "bd-3", "bd-4", "bd-4.1", "bd-5"}
+
+	// Start with some beads missing, so appends fill in dangling references
+	var lines []string
+	for _, id := range ids[:6] {
+		lines = append(lines, randomRecord(rng, id, ids, 0))
+	}
+	path := writeJSONL(t, lines...)
+	graph, err := BuildGraph(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := graph.Source.(AppendSource)
+
+	for step := 1; step <= 200; step++ {
+		var appended []string
+		for i := rng.Intn(3) + 1; i > 0; i-- {
+			appended = append(appended, randomRecord(rng, ids[rng.Intn(len(ids))], ids, step))
+		}
+		appendJSONL(t, path, appended...)
+		if !src.Appended() {
+			t.Fatal("append not detected")
+		}
+		if err := graph.LoadAppended(); err != nil {
+			t.Fatal(err)
+		}
+
+		full, err := BuildGraph(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, want := shape(graph.Snapshot()), shape(full.Snapshot())
+		for key, value := range want {
+			if got[key] != value {
+				t.Fatalf("step %d: %s\n got  %s\n want %s", step, key, got[key], value)
+			}
+		}
+		for key, value := range got {
+			if _, ok := want[key]; !ok {
+				t.Fatalf("step %d: unexpected %s: %s", step, key, value)
+			}
+		}
+	}
+}
+
+func TestAppended(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, path string)
+		want   bool
+	}{
+		{"unchanged", func(t *testing.T, path string) {}, false},
+		{"touched", func(t *testing.T, path string) {
+			now := time.Now().Add(time.Minute)
+			os.Chtimes(path, now, now)
+		}, false},
+		{"line appended", func(t *testing.T, path string) {
+			appendJSONL(t, path, record("bd-3", "three", 1))
+		}, true},
+		{"partial line appended", func(t *testing.T, path string) {
+			appendJSONL(t, path, `{"id":"bd-3"`)
+		}, true},
+		{"prefix rewritten and grown", func(t *testing.T, path string) {
+			rewriteJSONL(t, path, record("bd-1", "ONE", 1), record("bd-2", "two", 1), record("bd-3", "three", 1))
+		}, false},
+		{"prefix rewritten at the same size", func(t *testing.T, path string) {
+			rewriteJSONL(t, path, record("bd-1", "eno", 1), record("bd-2", "two", 1))
+		}, false},
+		{"truncated", func(t *testing.T, path string) {
+			rewriteJSONL(t, path, record("bd-1", "one", 1))
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeJSONL(t, record("bd-1", "one", 1), record("bd-2", "two", 1))
+			src := NewJSONLSource(path)
+			if _, err := src.Load(); err != nil {
+				t.Fatal(err)
+			}
+			tt.change(t, path)
+			if got := src.Appended(); got != tt.want {
+				t.Errorf("Appended() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadAppendedPartialLine(t *testing.T) {
+	path := writeJSONL(t, record("bd-1", "one", 1))
+	graph, err := BuildGraph(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing complete to merge, so no new generation
+	appendJSONL(t, path, `{"id":"bd-2",`)
+	if err := graph.LoadAppended(); err != nil {
+		t.Fatal(err)
+	}
+	if graph.Generation() != 1 {
+		t.Errorf("generation = %d after a partial line, want 1", graph.Generation())
+	}
+
+	appendJSONL(t, path, `"title":"two","status":"open","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}`+"\n")
+	if err := graph.LoadAppended(); err != nil {
+		t.Fatal(err)
+	}
+	if snap := graph.Snapshot(); snap.Generation != 2 || snap.GetBead("bd-2") == nil {
+		t.Errorf("generation %d, bd-2 = %v after completing the line", snap.Generation, snap.GetBead("bd-2"))
+	}
+}
+
+func TestLoadAppendedClearsTruncatedLineError(t *testing.T) {
+	// The first build publishes whatever it finds, a line mid-write included
+	path := writeJSONL(t, record("bd-1", "one", 1), "not json\n", `{"id":"bd-2","ti`)
+	graph, err := BuildGraph(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := graph.Snapshot().Errors; len(errs) != 2 || errs[1].Line != 3 {
+		t.Fatalf("errors = %v, want lines 2 and 3", errs)
+	}
+
+	appendJSONL(t, path, `tle":"two","status":"open","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}`+"\n", record("bd-3", "three", 1))
+	if !graph.Source.(AppendSource).Appended() {
+		t.Fatal("completed line not seen as appended")
+	}
+	if err := graph.LoadAppended(); err != nil {
+		t.Fatal(err)
+	}
+	snap := graph.Snapshot()
+	if snap.GetBead("bd-2") == nil || snap.GetBead("bd-3") == nil {
+		t.Error("appended beads not loaded")
+	}
+	if len(snap.Errors) != 1 || snap.Errors[0].Line != 2 {
+		t.Errorf("errors = %v, want only the one for line 2", snap.Errors)
+	}
+}
+
+func TestLoadAppendedAppliesReloadPolicy(t *testing.T) {
+	path := writeJSONL(t, records(4)...)
+	graph, err := BuildGraph(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appendJSONL(t, path, record("bd-5", "five", 1), "garbage\n", "{\n", "}\n")
+	var degraded *DegradedError
+	if err := graph.LoadAppended(); !errors.As(err, &degraded) {
+		t.Fatalf("err = %v, want a DegradedError", err)
+	}
+	if graph.Generation() != 1 || graph.Degraded() == nil {
+		t.Errorf("generation = %d, degraded = %v after a rejected append", graph.Generation(), graph.Degraded())
+	}
+
+	// A full rebuild, as the watcher does while degraded, sees the same lines
+	if err := graph.ForceRebuild(); err != nil {
+		t.Fatal(err)
+	}
+	if graph.Snapshot().GetBead("bd-5") == nil || graph.Degraded() != nil {
+		t.Error("forced rebuild didn't load the appended bead")
+	}
+}
+
+// benchmarkFile writes n beads: epics with ten children each, every child
+// blocked by its predecessor
+func benchmarkFile(b *testing.B, n int) string {
+	var content strings.Builder
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("bd-%d", i/10)
+		var deps []*Dependency
+		if i%10 != 0 {
+			id = fmt.Sprintf("bd-%d.%d", i/10, i%10)
+			if i%10 > 1 {
+				deps = append(deps, &Dependency{IssueID: id, DependsOnID: fmt.Sprintf("bd-%d.%d", i/10, i%10-1), Type: "blocks"})
+			}
+		}
+		line, _ := json.Marshal(&Bead{ID: id, Title: id, Status: StatusOpen, Dependencies: deps})
+		content.Write(line)
+		content.WriteString("\n")
+	}
+	path := filepath.Join(b.TempDir(), "issues.jsonl")
+	if err := os.WriteFile(path, []byte(content.String()), 0644); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+// BenchmarkAppend compares merging one appended edit into the previous
+// snapshot with rebuilding the whole graph. Merging should stay flat in
+// allocations as the graph grows: what's left scales with copying the ID map
+// and the index buckets the edit moves between, not with re-linking.
+func BenchmarkAppend(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		path := benchmarkFile(b, n)
+		graph, err := BuildGraph(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		snap := graph.Snapshot()
+		edit := &ParseResult{Beads: []*Bead{{
+			ID:         "bd-5.5",
+			ParentID:   "bd-5",
+			BlockerIDs: []string{"bd-5.4"},
+			Title:      "edited",
+			Status:     StatusClosed,
+			UpdatedAt:  time.Now(),
+		}}}
+
+		b.Run(fmt.Sprintf("merge/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				snap.merge(edit)
+			}
+		})
+		b.Run(fmt.Sprintf("rebuild/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := graph.Rebuild(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
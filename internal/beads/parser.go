@@ -2,11 +2,14 @@ package beads
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"hash/maphash"
 	"io"
 	"os"
 	"strings"
+	"unicode/utf8"
 )
 
 // ParseError represents an error parsing a specific line in the JSONL file
@@ -14,84 +17,158 @@ type ParseError struct {
 	Line    int
 	Message string
 	Err     error
+	Raw     string // Snippet of the offending line, truncated to maxRawSnippet bytes
 }
 
+// maxRawSnippet caps how much of a bad line is kept for diagnostics
+const maxRawSnippet = 200
+
 func (e *ParseError) Error() string {
 	return fmt.Sprintf("line %d: %s: %v", e.Line, e.Message, e.Err)
 }
 
-// ParseResult contains the result of parsing a JSONL file
-type ParseResult struct {
-	Beads    []*Bead
-	Errors   []*ParseError
-	FileSize int64
+// MarshalJSON renders the error for the diagnostics API
+func (e *ParseError) MarshalJSON() ([]byte, error) {
+	detail := ""
+	if e.Err != nil {
+		detail = e.Err.Error()
+	}
+	return json.Marshal(struct {
+		Line    int    `json:"line,omitempty"`
+		Message string `json:"message"`
+		Error   string `json:"error,omitempty"`
+		Raw     string `json:"raw,omitempty"`
+	}{e.Line, e.Message, detail, e.Raw})
 }
 
-// ParseJSONL parses a beads.jsonl file and returns all valid beads
-func ParseJSONL(filePath string) (*ParseResult, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+// rawSnippet truncates a line for inclusion in a ParseError
+func rawSnippet(line string) string {
+	if len(line) <= maxRawSnippet {
+		return line
 	}
-	defer file.Close()
-
-	stat, err := file.Stat()
-	if err != nil {
-		return nil, fmt.Errorf("failed to stat file: %w", err)
+	// Back up to a rune boundary so the snippet stays valid UTF-8
+	cut := maxRawSnippet
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
 	}
+	return line[:cut] + "…"
+}
 
-	result := &ParseResult{
-		Beads:    make([]*Bead, 0),
-		Errors:   make([]*ParseError, 0),
-		FileSize: stat.Size(),
-	}
+// ParseResult contains the result of parsing a JSONL file
+type ParseResult struct {
+	Beads      []*Bead
+	Tombstones []*Bead // Soft-deleted records, kept out of Beads
+	Errors     []*ParseError
+	FileSize   int64
+	Offset     int64 // Byte offset just past the last newline-terminated line
+	Lines      int   // Number of newline-terminated lines read
+	Truncated  bool  // The file ends in an unterminated line that failed to parse
+	PriorLines int   // Lines loaded before this result, for an incremental load
+
+	// Unresolved git conflict hunks found in the file
+	MergeConflicts []*MergeConflict
+}
 
-	scanner := bufio.NewScanner(file)
-	// Increase buffer size for long lines
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
+// DefaultMaxBeadSize is the longest JSONL line accepted as one bead
+const DefaultMaxBeadSize = 64 << 20
 
-	lineNum := 0
-	for scanner.Scan() {
-		lineNum++
-		line := strings.TrimSpace(scanner.Text())
+// ParseOptions tunes the JSONL parser
+type ParseOptions struct {
+	// MaxBeadSize is the longest line accepted as a single bead, in bytes.
+	// Longer lines are skipped with a ParseError and parsing carries on.
+	// 0 means no limit.
+	MaxBeadSize int64
+}
 
-		// Skip empty lines
-		if line == "" {
-			continue
-		}
+// DefaultParseOptions returns the options used unless overridden
+func DefaultParseOptions() ParseOptions {
+	return ParseOptions{MaxBeadSize: DefaultMaxBeadSize}
+}
 
-		bead, err := parseLine(line)
-		if err != nil {
-			result.Errors = append(result.Errors, &ParseError{
-				Line:    lineNum,
-				Message: "failed to parse bead",
-				Err:     err,
-			})
-			continue
-		}
+// lineReader streams newline-delimited records of any length and tracks how
+// far into the file complete (newline-terminated) lines reach
+type lineReader struct {
+	r       *bufio.Reader
+	maxSize int64
+	offset  int64
+	lines   int
+}
 
-		// Apply defaults
-		bead.SetDefaults()
+// next returns the next line and its size, both without the line ending
+// ("\n" or "\r\n"). Lines longer than maxSize are read to the end but only a
+// snippet is kept, with oversized set. terminated is false for a final line
+// with no newline. Returns io.EOF when there is nothing left.
+func (l *lineReader) next() (line []byte, size int64, oversized, terminated bool, err error) {
+	var read int64 // Bytes read, line ending included
+	var prev byte  // Last byte of the previous chunk, in case "\r\n" is split
+	for {
+		chunk, err := l.r.ReadSlice('\n')
+		read += int64(len(chunk))
+
+		// Leave room for a "\r\n" until the line ending is known
+		if !oversized && l.maxSize > 0 && read > l.maxSize+2 {
+			// Stop buffering; keep just enough for the error snippet
+			oversized = true
+			if len(line) > maxRawSnippet {
+				line = line[:maxRawSnippet]
+			}
+		}
+		if !oversized {
+			line = append(line, chunk...)
+		} else if len(line) < maxRawSnippet {
+			line = append(line, chunk[:min(len(chunk), maxRawSnippet-len(line))]...)
+		}
 
-		// Skip tombstones for display
-		if bead.IsTombstone() {
+		switch err {
+		case bufio.ErrBufferFull:
+			prev = chunk[len(chunk)-1]
 			continue
+		case nil:
+			l.offset += read
+			l.lines++
+			size = read - 1
+			if n := len(chunk); (n >= 2 && chunk[n-2] == '\r') || (n == 1 && prev == '\r') {
+				size--
+			}
+			line, oversized = l.limit(bytes.TrimRight(line, "\r\n"), size, oversized)
+			return line, size, oversized, true, nil
+		case io.EOF:
+			if read == 0 {
+				return nil, 0, false, false, io.EOF
+			}
+			line, oversized = l.limit(line, read, oversized)
+			return line, read, oversized, false, nil
+		default:
+			return nil, read, oversized, false, err
 		}
-
-		result.Beads = append(result.Beads, bead)
 	}
+}
 
-	if err := scanner.Err(); err != nil {
-		return result, fmt.Errorf("error reading file: %w", err)
+// limit flags a complete line whose content is over maxSize, keeping just a
+// snippet of it
+func (l *lineReader) limit(line []byte, size int64, oversized bool) ([]byte, bool) {
+	if oversized || l.maxSize <= 0 || size <= l.maxSize {
+		return line, oversized
 	}
+	return line[:min(len(line), maxRawSnippet)], true
+}
 
-	return result, nil
+// ParseJSONL parses a beads.jsonl file and returns all valid beads
+func ParseJSONL(filePath string, opts ParseOptions) (*ParseResult, error) {
+	return parseJSONLFile(filePath, 0, false, opts)
+}
+
+// ParseJSONLFromOffset parses a JSONL file starting from a specific byte offset.
+// Used for incremental updates: the offset must fall on a line boundary, and an
+// unterminated final line is left for the next call. Line numbers in errors
+// are relative to the offset.
+func ParseJSONLFromOffset(filePath string, offset int64, opts ParseOptions) (*ParseResult, error) {
+	return parseJSONLFile(filePath, offset, true, opts)
 }
 
-// ParseJSONLFromOffset parses a JSONL file starting from a specific byte offset
-// Used for incremental updates
-func ParseJSONLFromOffset(filePath string, offset int64) (*ParseResult, error) {
+// parseJSONLFile streams a JSONL file from offset. With completeOnly set, a
+// final line without a newline is treated as still being written and skipped.
+func parseJSONLFile(filePath string, offset int64, completeOnly bool, opts ParseOptions) (*ParseResult, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
@@ -117,43 +194,197 @@ func ParseJSONLFromOffset(filePath string, offset int64) (*ParseResult, error) {
 		FileSize: stat.Size(),
 	}
 
-	scanner := bufio.NewScanner(file)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
-
+	reader := &lineReader{
+		r:       bufio.NewReaderSize(file, 64*1024),
+		maxSize: opts.MaxBeadSize,
+		offset:  offset,
+	}
+	lines := &lineParser{result: result}
 	lineNum := 0
-	for scanner.Scan() {
-		lineNum++
-		line := strings.TrimSpace(scanner.Text())
-
-		if line == "" {
-			continue
+	var readErr error
+	for {
+		line, size, oversized, terminated, err := reader.next()
+		if err != nil {
+			if err != io.EOF {
+				readErr = err
+			}
+			break
+		}
+		if !terminated && completeOnly {
+			// A writer is still appending this line; pick it up next time
+			break
 		}
 
-		bead, err := parseLine(line)
-		if err != nil {
+		lineNum++
+		if oversized {
 			result.Errors = append(result.Errors, &ParseError{
 				Line:    lineNum,
-				Message: "failed to parse bead",
-				Err:     err,
+				Message: "bead exceeds maximum size",
+				Err:     fmt.Errorf("line is %d bytes, limit is %d", size, opts.MaxBeadSize),
+				Raw:     rawSnippet(string(line)),
 			})
 			continue
 		}
+		lines.handle(lineNum, string(line))
+	}
+	lines.finish(lineNum)
+	result.Offset = reader.offset
+	result.Lines = reader.lines
 
-		bead.SetDefaults()
+	// A broken final line with no newline usually means a writer is mid-append
+	if lineNum > reader.lines && len(result.Errors) > 0 {
+		result.Truncated = result.Errors[len(result.Errors)-1].Line == lineNum
+	}
 
-		if bead.IsTombstone() {
-			continue
+	if readErr != nil {
+		return result, fmt.Errorf("error reading file: %w", readErr)
+	}
+
+	return result, nil
+}
+
+// Git merge conflict markers. bd's JSONL is line-oriented, so a bad merge
+// leaves these on lines of their own between the competing records.
+const (
+	conflictStartMarker = "<<<<<<<"
+	conflictBaseMarker  = "|||||||"
+	conflictSplitMarker = "======="
+	conflictEndMarker   = ">>>>>>>"
+)
+
+// MergeConflict describes an unresolved git conflict hunk in the JSONL file
+type MergeConflict struct {
+	StartLine int      `json:"startLine"`
+	EndLine   int      `json:"endLine,omitempty"` // 0 if the hunk is never closed
+	BeadIDs   []string `json:"beadIds"`
+}
+
+// conflictSection tracks which side of a conflict hunk a line belongs to
+type conflictSection int
+
+const (
+	sectionNone   conflictSection = iota // Not inside a hunk
+	sectionOurs                          // Between <<<<<<< and ||||||| or =======
+	sectionBase                          // diff3 common ancestor, between ||||||| and =======
+	sectionTheirs                        // Between ======= and >>>>>>>
+)
+
+// fingerprintSeed keys record fingerprints, which only need to be stable
+// within one process
+var fingerprintSeed = maphash.MakeSeed()
+
+// lineParser turns raw JSONL lines into beads, recovering both sides of any
+// git conflict hunks. Records from both sides are kept and marked Conflicted;
+// duplicate resolution later picks the newer version.
+type lineParser struct {
+	result  *ParseResult
+	hunk    *MergeConflict
+	section conflictSection
+}
+
+func (p *lineParser) handle(lineNum int, raw string) {
+	line := strings.TrimSpace(raw)
+
+	// Skip empty lines
+	if line == "" {
+		return
+	}
+
+	if p.handleMarker(lineNum, line) {
+		return
+	}
+
+	// The common ancestor is older than both sides, so it never wins
+	if p.section == sectionBase {
+		return
+	}
+
+	bead, err := parseLine(line)
+	if err != nil {
+		p.result.Errors = append(p.result.Errors, &ParseError{
+			Line:    lineNum,
+			Message: "failed to parse bead",
+			Err:     err,
+			Raw:     rawSnippet(line),
+		})
+		return
+	}
+	bead.SourceLine = lineNum
+	bead.fingerprint = maphash.String(fingerprintSeed, line)
+
+	if p.hunk != nil {
+		bead.Conflicted = true
+		if !containsString(p.hunk.BeadIDs, bead.ID) {
+			p.hunk.BeadIDs = append(p.hunk.BeadIDs, bead.ID)
 		}
+	}
+
+	// Apply defaults
+	bead.SetDefaults()
 
-		result.Beads = append(result.Beads, bead)
+	// Skip tombstones for display
+	if bead.IsTombstone() {
+		p.result.Tombstones = append(p.result.Tombstones, bead)
+		return
 	}
 
-	if err := scanner.Err(); err != nil {
-		return result, fmt.Errorf("error reading file: %w", err)
+	p.result.Beads = append(p.result.Beads, bead)
+}
+
+// handleMarker consumes a conflict marker line, reporting whether line was one
+func (p *lineParser) handleMarker(lineNum int, line string) bool {
+	switch {
+	case strings.HasPrefix(line, conflictStartMarker):
+		if p.hunk != nil {
+			p.markerError(lineNum, "nested merge conflict marker", line)
+		}
+		p.hunk = &MergeConflict{StartLine: lineNum}
+		p.result.MergeConflicts = append(p.result.MergeConflicts, p.hunk)
+		p.section = sectionOurs
+
+	case strings.HasPrefix(line, conflictBaseMarker):
+		if p.section != sectionOurs {
+			p.markerError(lineNum, "unexpected merge conflict base marker", line)
+			return true
+		}
+		p.section = sectionBase
+
+	case strings.HasPrefix(line, conflictSplitMarker):
+		if p.section != sectionOurs && p.section != sectionBase {
+			p.markerError(lineNum, "unexpected merge conflict separator", line)
+			return true
+		}
+		p.section = sectionTheirs
+
+	case strings.HasPrefix(line, conflictEndMarker):
+		if p.hunk == nil {
+			p.markerError(lineNum, "unexpected merge conflict end marker", line)
+			return true
+		}
+		p.hunk.EndLine = lineNum
+		p.hunk = nil
+		p.section = sectionNone
+
+	default:
+		return false
 	}
+	return true
+}
 
-	return result, nil
+func (p *lineParser) markerError(lineNum int, message, line string) {
+	p.result.Errors = append(p.result.Errors, &ParseError{
+		Line:    lineNum,
+		Message: message,
+		Err:     fmt.Errorf("git merge conflict in JSONL"),
+		Raw:     rawSnippet(line),
+	})
+}
+
+// finish reports a conflict hunk that was still open at the end of input
+func (p *lineParser) finish(lastLine int) {
+	if p.hunk != nil {
+		p.markerError(lastLine, fmt.Sprintf("merge conflict starting at line %d is never closed", p.hunk.StartLine), "")
+	}
 }
 
 func parseLine(line string) (*Bead, error) {
@@ -199,3 +430,12 @@ func extractBlockerIDs(deps []*Dependency) []string {
 	}
 	return blockers
 }
+
+func containsString(list []string, target string) bool {
+	for _, s := range list {
+		if s == target {
+			return true
+		}
+	}
+	return false
+}
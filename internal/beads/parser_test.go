@@ -0,0 +1,250 @@
+package beads
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLineReaderLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		maxSize    int64
+		bufSize    int
+		line       string
+		size       int64
+		oversized  bool
+		terminated bool
+	}{
+		{"LF at limit", "0123456789\n", 10, 4096, "0123456789", 10, false, true},
+		{"CRLF at limit", "0123456789\r\n", 10, 4096, "0123456789", 10, false, true},
+		{"LF over limit", "0123456789a\n", 10, 4096, "0123456789a", 11, true, true},
+		{"CRLF over limit", "0123456789a\r\n", 10, 4096, "0123456789a", 11, true, true},
+		{"CRLF split across reads", strings.Repeat("x", 15) + "\r\n", 15, 16, strings.Repeat("x", 15), 15, false, true},
+		{"long line across reads", strings.Repeat("x", 40) + "\n", 100, 16, strings.Repeat("x", 40), 40, false, true},
+		{"unterminated at limit", "0123456789", 10, 4096, "0123456789", 10, false, false},
+		{"unterminated over limit", "0123456789a", 10, 4096, "0123456789a", 11, true, false},
+		{"no limit", strings.Repeat("x", 100) + "\n", 0, 16, strings.Repeat("x", 100), 100, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &lineReader{r: bufio.NewReaderSize(strings.NewReader(tt.input), tt.bufSize), maxSize: tt.maxSize}
+			line, size, oversized, terminated, err := reader.next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(line) != tt.line || size != tt.size || oversized != tt.oversized || terminated != tt.terminated {
+				t.Errorf("next() = %q, %d, oversized %v, terminated %v; want %q, %d, %v, %v",
+					line, size, oversized, terminated, tt.line, tt.size, tt.oversized, tt.terminated)
+			}
+			if _, _, _, _, err := reader.next(); err != io.EOF {
+				t.Errorf("second next() error = %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestLineReaderOversizedSnippet(t *testing.T) {
+	input := strings.Repeat("x", 10*maxRawSnippet) + "\nnext\n"
+	reader := &lineReader{r: bufio.NewReaderSize(strings.NewReader(input), 16), maxSize: 100}
+
+	line, size, oversized, _, err := reader.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !oversized || size != int64(10*maxRawSnippet) || len(line) != maxRawSnippet {
+		t.Errorf("oversized = %v, size = %d, kept %d bytes", oversized, size, len(line))
+	}
+
+	// Parsing carries on after an oversized line, and offsets cover it
+	line, _, oversized, _, err = reader.next()
+	if err != nil || oversized || string(line) != "next" {
+		t.Errorf("next line = %q, oversized %v, err %v", line, oversized, err)
+	}
+	if reader.offset != int64(len(input)) || reader.lines != 2 {
+		t.Errorf("offset = %d, lines = %d; want %d, 2", reader.offset, reader.lines, len(input))
+	}
+}
+
+func TestParseJSONLMaxBeadSizeCRLF(t *testing.T) {
+	line := record("bd-1", "title", 1)
+	crlf := strings.TrimSuffix(line, "\n") + "\r\n"
+	path := writeJSONL(t, crlf, crlf)
+
+	result, err := ParseJSONL(path, ParseOptions{MaxBeadSize: int64(len(line) - 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) != 0 || len(result.Beads) != 2 {
+		t.Errorf("got %d beads and %d errors for records exactly at the limit", len(result.Beads), len(result.Errors))
+	}
+}
+
+func TestParseJSONLMergeConflictHunks(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     []string
+		beads     int // Records kept, both sides included
+		conflicts []MergeConflict
+		errors    int
+	}{
+		{
+			name: "both sides kept",
+			lines: []string{
+				record("bd-1", "before", 1),
+				"<<<<<<< HEAD\n",
+				record("bd-2", "ours", 2),
+				"=======\n",
+				record("bd-2", "theirs", 3),
+				record("bd-3", "new on their side", 3),
+				">>>>>>> feature\n",
+				record("bd-4", "after", 1),
+			},
+			beads:     5,
+			conflicts: []MergeConflict{{StartLine: 2, EndLine: 7, BeadIDs: []string{"bd-2", "bd-3"}}},
+		},
+		{
+			name: "diff3 base skipped",
+			lines: []string{
+				"<<<<<<< HEAD\n",
+				record("bd-1", "ours", 2),
+				"||||||| base\n",
+				record("bd-1", "base", 1),
+				"=======\n",
+				record("bd-1", "theirs", 3),
+				">>>>>>> feature\n",
+			},
+			beads:     2,
+			conflicts: []MergeConflict{{StartLine: 1, EndLine: 7, BeadIDs: []string{"bd-1"}}},
+		},
+		{
+			name: "never closed",
+			lines: []string{
+				"<<<<<<< HEAD\n",
+				record("bd-1", "ours", 2),
+				"=======\n",
+				record("bd-1", "theirs", 3),
+			},
+			beads:     2,
+			conflicts: []MergeConflict{{StartLine: 1, BeadIDs: []string{"bd-1"}}},
+			errors:    1,
+		},
+		{
+			name: "stray markers",
+			lines: []string{
+				record("bd-1", "one", 1),
+				"=======\n",
+				">>>>>>> feature\n",
+			},
+			beads:  1,
+			errors: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseJSONL(writeJSONL(t, tt.lines...), ParseOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Beads) != tt.beads {
+				t.Errorf("beads = %d, want %d", len(result.Beads), tt.beads)
+			}
+			if len(result.Errors) != tt.errors {
+				t.Errorf("errors = %v, want %d", result.Errors, tt.errors)
+			}
+			if len(result.MergeConflicts) != len(tt.conflicts) {
+				t.Fatalf("merge conflicts = %d, want %d", len(result.MergeConflicts), len(tt.conflicts))
+			}
+			for i, want := range tt.conflicts {
+				got := result.MergeConflicts[i]
+				if got.StartLine != want.StartLine || got.EndLine != want.EndLine || strings.Join(got.BeadIDs, ",") != strings.Join(want.BeadIDs, ",") {
+					t.Errorf("merge conflict = %+v, want %+v", got, want)
+				}
+			}
+			for _, bead := range result.Beads {
+				inHunk := bead.Title == "ours" || bead.Title == "theirs" || bead.Title == "new on their side"
+				if bead.Conflicted != inHunk {
+					t.Errorf("%s %q: conflicted = %v", bead.ID, bead.Title, bead.Conflicted)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeConflictSidesCompete(t *testing.T) {
+	graph, err := BuildGraph(writeJSONL(t,
+		"<<<<<<< HEAD\n",
+		record("bd-1", "ours", 3),
+		"=======\n",
+		record("bd-1", "theirs", 2),
+		">>>>>>> feature\n",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := graph.Snapshot()
+
+	// The newer side wins even though it comes first
+	if got := snap.Beads["bd-1"].Title; got != "ours" {
+		t.Errorf("winner = %q, want ours", got)
+	}
+	conflict := snap.Conflicts["bd-1"]
+	if conflict == nil || len(conflict.Losers) != 1 || conflict.Losers[0].Bead.Title != "theirs" {
+		t.Fatalf("conflict = %+v", conflict)
+	}
+	if len(snap.GetMergeConflicts()) != 1 {
+		t.Errorf("merge conflicts = %d, want 1", len(snap.GetMergeConflicts()))
+	}
+}
+
+func TestDiagnosticsReportParseErrors(t *testing.T) {
+	graph, err := BuildGraph(writeJSONL(t,
+		`{"id":"bd-1","title":"one","status":"open","dependencies":[{"issue_id":"bd-1","depends_on_id":"bd-404","type":"blocks"}]}`+"\n",
+		"not json\n",
+		`{"id":"bd-2","status":"open"}`+"\n",
+		`{"id":"bd-9.1","title":"orphan","status":"open"}`+"\n",
+		`{"id":"bd-3","title":"odd","status":"someday","issue_type":"idea"}`+"\n",
+		"<<<<<<< HEAD\n",
+		record("bd-4", "ours", 1),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diag := graph.Snapshot().GetDiagnostics()
+
+	var lines []string
+	for _, parseErr := range diag.ParseErrors {
+		lines = append(lines, fmt.Sprintf("%d:%s", parseErr.Line, parseErr.Message))
+	}
+	want := "2:failed to parse bead,3:failed to parse bead,7:merge conflict starting at line 6 is never closed"
+	if got := strings.Join(lines, ","); got != want {
+		t.Errorf("parse errors = %s, want %s", got, want)
+	}
+	data, err := json.Marshal(diag.ParseErrors[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"raw":"not json"`) || !strings.Contains(string(data), `"error":"invalid JSON`) {
+		t.Errorf("parse error JSON = %s", data)
+	}
+
+	if len(diag.DanglingDependencies) != 1 || diag.DanglingDependencies[0].DependsOnID != "bd-404" {
+		t.Errorf("dangling dependencies = %+v", diag.DanglingDependencies)
+	}
+	if len(diag.OrphanedChildren) != 1 || diag.OrphanedChildren[0].ParentID != "bd-9" {
+		t.Errorf("orphaned children = %+v", diag.OrphanedChildren)
+	}
+	if len(diag.UnknownStatuses) != 1 || diag.UnknownStatuses[0].Value != "someday" {
+		t.Errorf("unknown statuses = %+v", diag.UnknownStatuses)
+	}
+	if len(diag.UnknownTypes) != 1 || diag.UnknownTypes[0].Value != "idea" {
+		t.Errorf("unknown types = %+v", diag.UnknownTypes)
+	}
+	if diag.Total() != 7 {
+		t.Errorf("total = %d, want 7", diag.Total())
+	}
+}
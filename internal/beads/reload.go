@@ -0,0 +1,104 @@
+package beads
+
+import (
+	"fmt"
+	"time"
+)
+
+// ReloadPolicy decides whether a freshly loaded graph looks complete enough
+// to replace the one being served
+type ReloadPolicy struct {
+	// MaxErrorRate is the largest fraction of lines that may fail to parse
+	MaxErrorRate float64
+
+	// MaxDropRatio is the largest fraction of beads that may disappear in one reload
+	MaxDropRatio float64
+
+	// MinBeadsForDropCheck skips the drop check for small projects,
+	// where deleting a handful of beads is a big fraction
+	MinBeadsForDropCheck int
+}
+
+// DefaultReloadPolicy returns the thresholds used unless overridden
+func DefaultReloadPolicy() ReloadPolicy {
+	return ReloadPolicy{
+		MaxErrorRate:         0.2,
+		MaxDropRatio:         0.5,
+		MinBeadsForDropCheck: 10,
+	}
+}
+
+// check returns why next should not replace a graph of prevCount beads,
+// or "" if it looks fine
+func (p ReloadPolicy) check(prevCount int, next *Snapshot, result *ParseResult) string {
+	if result.Truncated {
+		return "last line is truncated (file may still be being written)"
+	}
+
+	lines := len(result.Beads) + len(result.Tombstones) + len(result.Errors)
+	if lines > 0 && float64(len(result.Errors))/float64(lines) > p.MaxErrorRate {
+		return fmt.Sprintf("%d of %d lines failed to parse", len(result.Errors), lines)
+	}
+
+	if prevCount >= p.MinBeadsForDropCheck && float64(len(next.Beads)) < float64(prevCount)*(1-p.MaxDropRatio) {
+		return fmt.Sprintf("bead count dropped from %d to %d", prevCount, len(next.Beads))
+	}
+
+	return ""
+}
+
+// DegradedError is returned when a reload was rejected and the previous
+// graph is still being served
+type DegradedError struct {
+	Reason   string    `json:"reason"`
+	Since    time.Time `json:"since"`    // When the graph first became degraded
+	Attempts int       `json:"attempts"` // Consecutive rejected reloads
+}
+
+func (e *DegradedError) Error() string {
+	return fmt.Sprintf("reload rejected after %d attempt(s), serving previous data: %s", e.Attempts, e.Reason)
+}
+
+// Degraded returns the reason the graph is serving stale data, or nil if the
+// last reload succeeded
+func (g *BeadsGraph) Degraded() *DegradedError {
+	return g.degraded.Load()
+}
+
+// degrade records a rejected reload. Callers must hold the write lock.
+func (g *BeadsGraph) degrade(reason string) *DegradedError {
+	next := &DegradedError{Since: time.Now()}
+	if prev := g.degraded.Load(); prev != nil {
+		*next = *prev
+	}
+	next.Reason = reason
+	next.Attempts++
+
+	g.degraded.Store(next)
+	return next
+}
+
+// ReloadStats counts a graph's reloads since it was built
+type ReloadStats struct {
+	Reloads  uint64        // Full and incremental reloads, including rejected ones
+	Failures uint64        // Reloads that failed or were rejected
+	Duration time.Duration // Total time spent reloading
+}
+
+// ReloadStats returns how often the graph has reloaded and how long it took
+func (g *BeadsGraph) ReloadStats() ReloadStats {
+	return ReloadStats{
+		Reloads:  g.reloads.Load(),
+		Failures: g.reloadFailures.Load(),
+		Duration: time.Duration(g.reloadTime.Load()),
+	}
+}
+
+// recordReload counts a reload that started at start and returned *err
+func (g *BeadsGraph) recordReload(start time.Time, err *error) {
+	g.reloads.Add(1)
+	g.reloadTime.Add(int64(time.Since(start)))
+	if *err != nil {
+		g.reloadFailures.Add(1)
+	}
+}
@@ -0,0 +1,166 @@
+package beads
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// records returns JSONL lines for beads bd-1 to bd-n
+func records(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = record(fmt.Sprintf("bd-%d", i+1), "title", 1)
+	}
+	return lines
+}
+
+func rewriteJSONL(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	content := ""
+	for _, line := range lines {
+		content += line
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadPolicyCheck(t *testing.T) {
+	policy := DefaultReloadPolicy()
+	tests := []struct {
+		name      string
+		prevCount int
+		result    *ParseResult
+		rejected  bool
+	}{
+		{"unchanged", 20, &ParseResult{Beads: make([]*Bead, 20)}, false},
+		{"truncated", 20, &ParseResult{Beads: make([]*Bead, 20), Truncated: true}, true},
+		{"few parse errors", 20, &ParseResult{Beads: make([]*Bead, 20), Errors: make([]*ParseError, 2)}, false},
+		{"many parse errors", 20, &ParseResult{Beads: make([]*Bead, 20), Errors: make([]*ParseError, 10)}, true},
+		{"shrunk by half", 20, &ParseResult{Beads: make([]*Bead, 10)}, false},
+		{"shrunk by more than half", 20, &ParseResult{Beads: make([]*Bead, 9)}, true},
+		{"small project emptied", 9, &ParseResult{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := newSnapshot()
+			for i := range tt.result.Beads {
+				next.Beads[fmt.Sprintf("bd-%d", i)] = &Bead{}
+			}
+			reason := policy.check(tt.prevCount, next, tt.result)
+			if (reason != "") != tt.rejected {
+				t.Errorf("reason = %q, rejected = %v", reason, tt.rejected)
+			}
+		})
+	}
+}
+
+func TestRebuildKeepsPreviousSnapshotUntilRecovery(t *testing.T) {
+	path := writeJSONL(t, records(20)...)
+	graph, err := BuildGraph(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generation := graph.Generation()
+
+	// A file cut short is rejected, again and again, and the old data kept
+	rewriteJSONL(t, path, records(5)...)
+	for attempt := 1; attempt <= 2; attempt++ {
+		err := graph.Rebuild()
+		var degraded *DegradedError
+		if !errors.As(err, &degraded) {
+			t.Fatalf("attempt %d: err = %v, want a DegradedError", attempt, err)
+		}
+		if degraded.Attempts != attempt {
+			t.Errorf("attempts = %d, want %d", degraded.Attempts, attempt)
+		}
+		if graph.Degraded() == nil {
+			t.Error("graph not marked degraded")
+		}
+		if got := len(graph.Snapshot().Beads); got != 20 {
+			t.Errorf("serving %d beads, want the previous 20", got)
+		}
+		if graph.Generation() != generation {
+			t.Error("rejected reload published a snapshot")
+		}
+	}
+	if stats := graph.ReloadStats(); stats.Reloads != 2 || stats.Failures != 2 {
+		t.Errorf("reload stats = %+v", stats)
+	}
+
+	// The finished write passes and clears the degraded state
+	rewriteJSONL(t, path, records(19)...)
+	if err := graph.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	if graph.Degraded() != nil {
+		t.Error("still degraded after a good reload")
+	}
+	if got := len(graph.Snapshot().Beads); got != 19 {
+		t.Errorf("serving %d beads, want 19", got)
+	}
+}
+
+func TestForceRebuildBypassesPolicy(t *testing.T) {
+	path := writeJSONL(t, records(20)...)
+	graph, err := BuildGraph(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rewriteJSONL(t, path, records(3)...)
+	if err := graph.Rebuild(); err == nil {
+		t.Fatal("shrunk file accepted")
+	}
+	if err := graph.ForceRebuild(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(graph.Snapshot().Beads); got != 3 {
+		t.Errorf("serving %d beads, want 3", got)
+	}
+	if graph.Degraded() != nil {
+		t.Error("still degraded after a forced reload")
+	}
+}
+
+func TestWatcherRetriesThenForcesRebuild(t *testing.T) {
+	path := writeJSONL(t, records(20)...)
+	graph, err := BuildGraph(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var degraded, recovered, changed int
+	w := &Watcher{
+		graph:       graph,
+		onDegraded:  func(*DegradedError) { degraded++ },
+		onRecovered: func() { recovered++ },
+		onChange:    func() { changed++ },
+	}
+
+	rewriteJSONL(t, path, records(3)...)
+	for attempt := 1; attempt <= maxReloadRetries; attempt++ {
+		if retry := w.reloadAndNotify(); retry == nil {
+			t.Fatalf("attempt %d: no retry scheduled", attempt)
+		}
+		if got := len(graph.Snapshot().Beads); got != 20 {
+			t.Fatalf("attempt %d: serving %d beads, want the previous 20", attempt, got)
+		}
+	}
+	if degraded != maxReloadRetries || changed != 0 {
+		t.Errorf("degraded = %d, changed = %d", degraded, changed)
+	}
+
+	// The file stayed the same across every retry, so it's loaded anyway
+	if retry := w.reloadAndNotify(); retry != nil {
+		t.Error("retry scheduled after forcing the rebuild")
+	}
+	if got := len(graph.Snapshot().Beads); got != 3 {
+		t.Errorf("serving %d beads, want 3", got)
+	}
+	if recovered != 1 || changed != 1 || graph.Degraded() != nil {
+		t.Errorf("recovered = %d, changed = %d, degraded = %v", recovered, changed, graph.Degraded())
+	}
+}
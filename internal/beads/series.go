@@ -0,0 +1,150 @@
+package beads
+
+import (
+	"fmt"
+	"time"
+)
+
+// Interval is the width of the buckets in a series
+type Interval string
+
+const (
+	IntervalDay  Interval = "day"
+	IntervalWeek Interval = "week" // Starting on Monday
+)
+
+// MaxSeriesPoints bounds how many intervals one series may span
+const MaxSeriesPoints = 1000
+
+// SeriesPoint counts beads over one interval. Open and Closed are the state
+// at the end of the interval, for burndown and cumulative-flow charts;
+// Created and Completed are what happened during it.
+type SeriesPoint struct {
+	Date      string `json:"date"` // First day of the interval, YYYY-MM-DD
+	Open      int    `json:"open"`
+	Closed    int    `json:"closed"`
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+}
+
+// ParseInterval checks a series interval, defaulting to a day
+func ParseInterval(value string) (Interval, error) {
+	switch Interval(value) {
+	case "", IntervalDay:
+		return IntervalDay, nil
+	case IntervalWeek:
+		return IntervalWeek, nil
+	}
+	return "", fmt.Errorf("invalid interval %q: use day or week", value)
+}
+
+// start returns the UTC start of the interval containing t
+func (i Interval) start(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if i == IntervalWeek {
+		offset := (int(day.Weekday()) + 6) % 7 // Days since Monday
+		day = day.AddDate(0, 0, -offset)
+	}
+	return day
+}
+
+// Days returns how many days the interval spans
+func (i Interval) Days() int {
+	if i == IntervalWeek {
+		return 7
+	}
+	return 1
+}
+
+// length returns how long the interval is. Series are in UTC, so every day
+// is 24 hours.
+func (i Interval) length() time.Duration {
+	return time.Duration(i.Days()) * 24 * time.Hour
+}
+
+// GetSeries rebuilds the history of the beads matching filter, one point per
+// interval from the one containing from to the one containing to (in UTC).
+// Beads carry no status history, so a bead counts as closed from its
+// closed_at (or its last update, if that's missing) only while it is still
+// closed; reopened beads count as open all along.
+func (s *Snapshot) GetSeries(filter *Filter, from, to time.Time, interval Interval) ([]*SeriesPoint, error) {
+	var starts []time.Time
+	step := interval.length()
+	for t := interval.start(from); !t.After(to); t = t.Add(step) {
+		if len(starts) == MaxSeriesPoints {
+			return nil, fmt.Errorf("series spans more than %d %ss", MaxSeriesPoints, interval)
+		}
+		starts = append(starts, t)
+	}
+	if len(starts) == 0 {
+		return nil, fmt.Errorf("from must not be after to")
+	}
+
+	// index returns the bucket t falls in: -1 before the series, len(starts)
+	// or more after it
+	index := func(t time.Time) int {
+		if t.Before(starts[0]) {
+			return -1
+		}
+		return int(t.Sub(starts[0]) / step)
+	}
+
+	created := make([]int, len(starts))
+	completed := make([]int, len(starts))
+	var createdBefore, completedBefore int
+
+	for _, bead := range s.Beads {
+		if bead.IsTombstone() || bead.CreatedAt.IsZero() || !matchesFilter(bead, filter, s.Beads) {
+			continue
+		}
+
+		switch i := index(bead.CreatedAt); {
+		case i < 0:
+			createdBefore++
+		case i < len(starts):
+			created[i]++
+		}
+
+		closedAt, ok := closeTime(bead)
+		if !ok {
+			continue
+		}
+		switch i := index(closedAt); {
+		case i < 0:
+			completedBefore++
+		case i < len(starts):
+			completed[i]++
+		}
+	}
+
+	points := make([]*SeriesPoint, len(starts))
+	totalCreated, totalCompleted := createdBefore, completedBefore
+	for i, start := range starts {
+		totalCreated += created[i]
+		totalCompleted += completed[i]
+		points[i] = &SeriesPoint{
+			Date:      start.Format("2006-01-02"),
+			Open:      totalCreated - totalCompleted,
+			Closed:    totalCompleted,
+			Created:   created[i],
+			Completed: completed[i],
+		}
+	}
+	return points, nil
+}
+
+// closeTime returns when a closed bead was closed, never before it was created
+func closeTime(bead *Bead) (time.Time, bool) {
+	if bead.Status != StatusClosed {
+		return time.Time{}, false
+	}
+	closedAt := bead.UpdatedAt
+	if bead.ClosedAt != nil {
+		closedAt = *bead.ClosedAt
+	}
+	if closedAt.Before(bead.CreatedAt) {
+		closedAt = bead.CreatedAt
+	}
+	return closedAt, true
+}
@@ -0,0 +1,127 @@
+package beads
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// datedRecord returns a JSONL line for a bead created on the given date,
+// closed on closed if it isn't empty
+func datedRecord(id, issueType, status, created, closed string) string {
+	line := fmt.Sprintf(`{"id":%q,"title":%q,"issue_type":%q,"status":%q,"created_at":"%sT09:00:00Z","updated_at":"%sT09:00:00Z"`,
+		id, id, issueType, status, created, created)
+	if closed != "" {
+		line += fmt.Sprintf(`,"closed_at":"%sT17:00:00Z"`, closed)
+	}
+	return line + "}\n"
+}
+
+func date(value string) time.Time {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestGetSeries(t *testing.T) {
+	graph, err := BuildGraph(writeJSONL(t,
+		datedRecord("bd-1", "epic", "open", "2026-01-01", ""),
+		datedRecord("bd-1.1", "task", "closed", "2026-01-02", "2026-01-04"),
+		datedRecord("bd-1.1.1", "task", "open", "2026-01-04", ""),
+		datedRecord("bd-1.2", "task", "open", "2026-01-06", ""),
+		datedRecord("bd-2", "bug", "closed", "2025-12-30", "2026-01-03"),
+		// Closed without closed_at: its last update counts
+		`{"id":"bd-3","title":"three","status":"closed","created_at":"2026-01-03T09:00:00Z","updated_at":"2026-01-05T09:00:00Z"}`+"\n",
+		// Reopened beads count as open all along
+		datedRecord("bd-4", "task", "open", "2026-01-02", "2026-01-03"),
+		datedRecord("bd-5", "task", "open", "2026-01-20", ""),
+		datedRecord("bd-6", "task", "tombstone", "2026-01-02", ""),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := graph.Snapshot()
+
+	// Each point is "date open/closed created/completed"
+	tests := []struct {
+		name     string
+		filter   *Filter
+		interval Interval
+		want     []string
+	}{
+		{"daily", &Filter{}, IntervalDay, []string{
+			"2026-01-01 2/0 1/0",
+			"2026-01-02 4/0 2/0",
+			"2026-01-03 4/1 1/1",
+			"2026-01-04 4/2 1/1",
+			"2026-01-05 3/3 0/1",
+			"2026-01-06 4/3 1/0",
+		}},
+		{"weekly from Monday", &Filter{}, IntervalWeek, []string{
+			"2025-12-29 4/2 6/2",
+			"2026-01-05 4/3 1/1",
+		}},
+		{"epic", &Filter{Epic: "bd-1"}, IntervalDay, []string{
+			"2026-01-01 0/0 0/0",
+			"2026-01-02 1/0 1/0",
+			"2026-01-03 1/0 0/0",
+			"2026-01-04 1/1 1/1",
+			"2026-01-05 1/1 0/0",
+			"2026-01-06 2/1 1/0",
+		}},
+		{"epic without descendants", &Filter{Epic: "bd-2"}, IntervalWeek, []string{
+			"2025-12-29 0/0 0/0",
+			"2026-01-05 0/0 0/0",
+		}},
+		{"type", &Filter{Type: []BeadType{TypeBug}}, IntervalWeek, []string{
+			"2025-12-29 0/1 1/1",
+			"2026-01-05 0/1 0/0",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, err := snap.GetSeries(tt.filter, date("2026-01-01"), date("2026-01-06"), tt.interval)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, p := range points {
+				got = append(got, fmt.Sprintf("%s %d/%d %d/%d", p.Date, p.Open, p.Closed, p.Created, p.Completed))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("series:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestGetSeriesRange(t *testing.T) {
+	snap := newSnapshot()
+	tests := []struct {
+		name     string
+		from, to string
+		interval Interval
+		points   int
+		err      bool
+	}{
+		{"one day", "2026-01-01", "2026-01-01", IntervalDay, 1, false},
+		{"week containing both", "2026-01-06", "2026-01-11", IntervalWeek, 1, false},
+		{"reversed", "2026-01-02", "2026-01-01", IntervalDay, 0, true},
+		{"too long", "2020-01-01", "2026-01-01", IntervalDay, 0, true},
+		{"long in weeks", "2020-01-01", "2026-01-01", IntervalWeek, 314, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, err := snap.GetSeries(nil, date(tt.from), date(tt.to), tt.interval)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error %v", err, tt.err)
+			}
+			if len(points) != tt.points {
+				t.Errorf("points = %d, want %d", len(points), tt.points)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package beads
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DataSource is a backend that beads can be loaded from.
+// The graph, the watcher and the server all go through a DataSource so that
+// every backend gets the same reload and live-update behavior.
+type DataSource interface {
+	// Load reads every bead from the backing store
+	Load() (*ParseResult, error)
+
+	// Describe returns a short label for logs and API responses.
+	// It must not contain absolute filesystem paths.
+	Describe() string
+
+	// File returns the base name of the backing file, or "" if there isn't one
+	File() string
+
+	// Backend names the kind of store, like "jsonl" or "sqlite"
+	Backend() string
+
+	// WatchTargets returns the files whose changes should trigger a reload
+	WatchTargets() []string
+}
+
+// AppendSource is implemented by sources whose backing file usually only grows,
+// such as a JSONL file that agents append records to. It lets a reload parse
+// just the new tail instead of the whole file.
+type AppendSource interface {
+	DataSource
+
+	// Appended reports whether the data grew since the last load and only
+	// grew: the previously loaded bytes are unchanged and new ones follow them
+	Appended() bool
+
+	// LoadAppended parses the records added since the last load
+	LoadAppended() (*ParseResult, error)
+}
+
+// JSONLSource loads beads from a beads.jsonl / issues.jsonl file
+type JSONLSource struct {
+	Path    string
+	Options ParseOptions
+
+	mu       sync.Mutex
+	loaded   int64  // Offset just past the last complete line loaded
+	lines    int    // Number of complete lines loaded
+	checksum uint32 // CRC-32 of the file's first loaded bytes
+}
+
+// NewJSONLSource creates a DataSource for a JSONL file
+func NewJSONLSource(path string) *JSONLSource {
+	return &JSONLSource{Path: path, Options: DefaultParseOptions()}
+}
+
+// Load parses the whole JSONL file
+func (s *JSONLSource) Load() (*ParseResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := ParseJSONL(s.Path, s.Options)
+	if err != nil {
+		return result, err
+	}
+	s.remember(0, 0, result.Offset)
+	s.lines = result.Lines
+	return result, nil
+}
+
+// Appended reports whether the file grew past the last load with its
+// previously loaded prefix unchanged. Any error counts as a rewrite.
+func (s *JSONLSource) Appended() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stat, err := os.Stat(s.Path)
+	if err != nil || stat.Size() <= s.loaded {
+		return false
+	}
+	sum, err := checksumRange(s.Path, 0, s.loaded, 0)
+	if err != nil {
+		return false
+	}
+	return sum == s.checksum
+}
+
+// LoadAppended parses only the lines written after the last load.
+// Line numbers in the result are rebased to count from the start of the file.
+func (s *JSONLSource) LoadAppended() (*ParseResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := ParseJSONLFromOffset(s.Path, s.loaded, s.Options)
+	if err != nil {
+		return result, err
+	}
+	for _, bead := range result.Beads {
+		bead.SourceLine += s.lines
+	}
+	for _, bead := range result.Tombstones {
+		bead.SourceLine += s.lines
+	}
+	for _, parseErr := range result.Errors {
+		parseErr.Line += s.lines
+	}
+	for _, hunk := range result.MergeConflicts {
+		hunk.StartLine += s.lines
+		if hunk.EndLine > 0 {
+			hunk.EndLine += s.lines
+		}
+	}
+
+	result.PriorLines = s.lines
+	s.remember(s.loaded, s.checksum, result.Offset)
+	s.lines += result.Lines
+	return result, nil
+}
+
+// remember extends the loaded prefix checksum from start to end.
+// If the tail can't be re-read, the checksum is left stale so the next
+// Appended check fails and forces a full reload.
+func (s *JSONLSource) remember(start int64, sum uint32, end int64) {
+	if next, err := checksumRange(s.Path, start, end, sum); err == nil {
+		sum = next
+	}
+	s.checksum = sum
+	s.loaded = end
+}
+
+// checksumRange continues a CRC-32 over bytes [start, end) of a file
+func checksumRange(path string, start, end int64, sum uint32) (uint32, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	section := io.NewSectionReader(file, start, end-start)
+	buf := make([]byte, 64*1024)
+	var read int64
+	for {
+		n, err := section.Read(buf)
+		sum = crc32.Update(sum, crc32.IEEETable, buf[:n])
+		read += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if read != end-start {
+		return 0, fmt.Errorf("short read: got %d of %d bytes", read, end-start)
+	}
+	return sum, nil
+}
+
+// Describe returns the file name and backend
+func (s *JSONLSource) Describe() string {
+	return s.File() + " (JSONL)"
+}
+
+// File returns the JSONL file's base name
+func (s *JSONLSource) File() string {
+	return filepath.Base(s.Path)
+}
+
+// Backend returns "jsonl"
+func (s *JSONLSource) Backend() string {
+	return "jsonl"
+}
+
+// WatchTargets returns the JSONL file itself
+func (s *JSONLSource) WatchTargets() []string {
+	return []string{s.Path}
+}
+
+// SQLiteSource loads beads from a bd SQLite database (beads.db)
+type SQLiteSource struct {
+	Path string
+}
+
+// NewSQLiteSource creates a DataSource for a SQLite database
+func NewSQLiteSource(path string) *SQLiteSource {
+	return &SQLiteSource{Path: path}
+}
+
+// Load reads all beads from the database
+func (s *SQLiteSource) Load() (*ParseResult, error) {
+	return ParseSQLite(s.Path)
+}
+
+// Describe returns the file name and backend
+func (s *SQLiteSource) Describe() string {
+	return s.File() + " (SQLite)"
+}
+
+// File returns the database's base name
+func (s *SQLiteSource) File() string {
+	return filepath.Base(s.Path)
+}
+
+// Backend returns "sqlite"
+func (s *SQLiteSource) Backend() string {
+	return "sqlite"
+}
+
+// WatchTargets returns the database and its write-ahead log.
+// bd runs SQLite in WAL mode, so most commits only touch the -wal file
+// until a checkpoint folds them back into the main database.
+func (s *SQLiteSource) WatchTargets() []string {
+	return []string{s.Path, s.Path + "-wal"}
+}
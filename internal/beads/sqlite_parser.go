@@ -71,23 +71,28 @@ func getTableColumns(db *sql.DB, tableName string) (map[string]bool, error) {
 
 // buildSelectQuery builds a SELECT query based on available columns
 func buildSelectQuery(tableName string, columns map[string]bool) string {
-	// Map of our expected fields to possible column names
-	fieldMappings := map[string][]string{
-		"id":          {"id", "issue_id", "bead_id"},
-		"title":       {"title", "name", "summary"},
-		"description": {"description", "body", "content", "details"},
-		"status":      {"status", "state"},
-		"issue_type":  {"issue_type", "type", "kind", "category"},
-		"priority":    {"priority", "importance", "severity"},
-		"assignee":    {"assignee", "assigned_to", "owner"},
-		"created_at":  {"created_at", "created", "create_time"},
-		"updated_at":  {"updated_at", "updated", "update_time", "modified_at"},
-		"closed_at":   {"closed_at", "closed", "resolved_at"},
-		"parent_id":   {"parent_id", "parent", "epic_id"},
+	// Our expected fields and their possible column names.
+	// Order matters: it must match the Scan call in ParseSQLite.
+	fieldMappings := []struct {
+		field         string
+		possibleNames []string
+	}{
+		{"id", []string{"id", "issue_id", "bead_id"}},
+		{"title", []string{"title", "name", "summary"}},
+		{"description", []string{"description", "body", "content", "details"}},
+		{"status", []string{"status", "state"}},
+		{"issue_type", []string{"issue_type", "type", "kind", "category"}},
+		{"priority", []string{"priority", "importance", "severity"}},
+		{"assignee", []string{"assignee", "assigned_to", "owner"}},
+		{"created_at", []string{"created_at", "created", "create_time"}},
+		{"updated_at", []string{"updated_at", "updated", "update_time", "modified_at"}},
+		{"closed_at", []string{"closed_at", "closed", "resolved_at"}},
+		{"parent_id", []string{"parent_id", "parent", "epic_id"}},
 	}
 
 	var selectParts []string
-	for field, possibleNames := range fieldMappings {
+	for _, mapping := range fieldMappings {
+		field, possibleNames := mapping.field, mapping.possibleNames
 		found := false
 		for _, colName := range possibleNames {
 			if columns[colName] {
@@ -195,6 +200,11 @@ func ParseSQLite(dbPath string) (*ParseResult, error) {
 			ParentID:    parentID,
 		}
 
+		// Match the JSONL parser, which derives the parent from the ID
+		if bead.ParentID == "" {
+			bead.ParentID = extractParentID(id)
+		}
+
 		// Parse timestamps
 		if t, err := parseTime(createdAt); err == nil {
 			bead.CreatedAt = t
@@ -216,8 +226,14 @@ func ParseSQLite(dbPath string) (*ParseResult, error) {
 		return result, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	// Try to load labels from a separate table if it exists
-	loadLabels(db, result.Beads)
+	// Load relational data from bd's side tables if they exist
+	beadMap := make(map[string]*Bead, len(result.Beads))
+	for _, b := range result.Beads {
+		beadMap[b.ID] = b
+	}
+	loadLabels(db, beadMap)
+	loadDependencies(db, beadMap, result)
+	loadComments(db, beadMap, result)
 
 	return result, nil
 }
@@ -240,83 +256,180 @@ func parseTime(s string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("unable to parse time: %s", s)
 }
 
-// loadLabels tries to load labels from a labels or issue_labels table
-func loadLabels(db *sql.DB, beads []*Bead) {
-	// Create a map for quick lookup
-	beadMap := make(map[string]*Bead)
-	for _, b := range beads {
-		beadMap[b.ID] = b
+// firstColumn returns the first of the candidate names present in columns
+func firstColumn(columns map[string]bool, candidates ...string) string {
+	for _, name := range candidates {
+		if columns[name] {
+			return name
+		}
 	}
+	return ""
+}
 
-	// Try to query labels - ignore errors if table doesn't exist
-	rows, err := db.Query(`
-		SELECT issue_id, label 
-		FROM issue_labels
-	`)
-	if err != nil {
-		// Table might not exist, try alternative
-		rows, err = db.Query(`
-			SELECT issue_id, name 
-			FROM labels
-		`)
+// firstColumnOr is firstColumn with a fallback for tables missing every candidate
+func firstColumnOr(columns map[string]bool, fallback string, candidates ...string) string {
+	if col := firstColumn(columns, candidates...); col != "" {
+		return col
+	}
+	return fallback
+}
+
+// columnOrDefault returns a select expression for the first matching column,
+// or the literal fallback if the table has none of the candidates
+func columnOrDefault(columns map[string]bool, fallback string, candidates ...string) string {
+	if col := firstColumn(columns, candidates...); col != "" {
+		return fmt.Sprintf("COALESCE(%s, %s)", col, fallback)
+	}
+	return fallback
+}
+
+// loadLabels tries to load labels from a labels or issue_labels table
+func loadLabels(db *sql.DB, beadMap map[string]*Bead) {
+	for _, tableName := range []string{"labels", "issue_labels"} {
+		columns, err := getTableColumns(db, tableName)
+		if err != nil || len(columns) == 0 {
+			continue
+		}
+
+		issueCol := firstColumn(columns, "issue_id", "bead_id")
+		labelCol := firstColumn(columns, "label", "name")
+		if issueCol == "" || labelCol == "" {
+			continue
+		}
+
+		rows, err := db.Query(fmt.Sprintf("SELECT %s, %s FROM %s", issueCol, labelCol, tableName))
 		if err != nil {
-			return // No labels table, that's fine
+			continue
 		}
+
+		for rows.Next() {
+			var issueID, label string
+			if err := rows.Scan(&issueID, &label); err == nil {
+				if bead, ok := beadMap[issueID]; ok {
+					bead.Labels = append(bead.Labels, label)
+				}
+			}
+		}
+		rows.Close()
+		return
+	}
+}
+
+// loadDependencies loads bd's dependencies table and derives blocker IDs
+func loadDependencies(db *sql.DB, beadMap map[string]*Bead, result *ParseResult) {
+	columns, err := getTableColumns(db, "dependencies")
+	if err != nil || len(columns) == 0 {
+		return // No dependencies table, that's fine
+	}
+
+	issueCol := firstColumn(columns, "issue_id", "bead_id")
+	dependsOnCol := firstColumn(columns, "depends_on_id", "depends_on", "blocker_id")
+	if issueCol == "" || dependsOnCol == "" {
+		return
+	}
+
+	query := fmt.Sprintf("SELECT %s, %s, %s, %s, %s, %s, %s FROM dependencies",
+		issueCol,
+		dependsOnCol,
+		columnOrDefault(columns, "'blocks'", "type", "dep_type", "dependency_type"),
+		columnOrDefault(columns, "''", "created_at", "created"),
+		columnOrDefault(columns, "''", "created_by"),
+		columnOrDefault(columns, "''", "metadata"),
+		columnOrDefault(columns, "''", "thread_id"),
+	)
+	rows, err := db.Query(query)
+	if err != nil {
+		result.Errors = append(result.Errors, &ParseError{
+			Message: "failed to query dependencies",
+			Err:     err,
+		})
+		return
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var issueID, label string
-		if err := rows.Scan(&issueID, &label); err == nil {
-			if bead, ok := beadMap[issueID]; ok {
-				bead.Labels = append(bead.Labels, label)
-			}
+		var (
+			dep       Dependency
+			depType   string
+			createdAt string
+		)
+		if err := rows.Scan(&dep.IssueID, &dep.DependsOnID, &depType, &createdAt, &dep.CreatedBy, &dep.Metadata, &dep.ThreadID); err != nil {
+			result.Errors = append(result.Errors, &ParseError{
+				Message: "failed to scan dependency",
+				Err:     err,
+			})
+			continue
+		}
+		dep.Type = DependencyType(depType)
+		if t, err := parseTime(createdAt); err == nil {
+			dep.CreatedAt = t
+		}
+
+		if bead, ok := beadMap[dep.IssueID]; ok {
+			d := dep
+			bead.Dependencies = append(bead.Dependencies, &d)
 		}
 	}
+
+	for _, bead := range beadMap {
+		bead.BlockerIDs = extractBlockerIDs(bead.Dependencies)
+	}
 }
 
-// BuildGraphFromSQLite builds a graph from a SQLite database
-func BuildGraphFromSQLite(dbPath string) (*BeadsGraph, error) {
-	result, err := ParseSQLite(dbPath)
-	if err != nil {
-		return nil, err
+// loadComments loads bd's comments table
+func loadComments(db *sql.DB, beadMap map[string]*Bead, result *ParseResult) {
+	columns, err := getTableColumns(db, "comments")
+	if err != nil || len(columns) == 0 {
+		return // No comments table, that's fine
 	}
 
-	graph := NewGraph()
-	graph.FilePath = dbPath
-	graph.FileSize = result.FileSize
-	graph.LastUpdated = time.Now()
+	issueCol := firstColumn(columns, "issue_id", "bead_id")
+	if issueCol == "" {
+		return
+	}
 
-	// Add all beads to the map
-	for _, bead := range result.Beads {
-		graph.Beads[bead.ID] = bead
+	query := fmt.Sprintf("SELECT %s, %s, %s, %s, %s FROM comments ORDER BY %s",
+		columnOrDefault(columns, "0", "id"),
+		issueCol,
+		columnOrDefault(columns, "''", "author", "created_by"),
+		columnOrDefault(columns, "''", "text", "body", "content"),
+		columnOrDefault(columns, "''", "created_at", "created"),
+		firstColumnOr(columns, "rowid", "created_at", "id"),
+	)
+	rows, err := db.Query(query)
+	if err != nil {
+		result.Errors = append(result.Errors, &ParseError{
+			Message: "failed to query comments",
+			Err:     err,
+		})
+		return
 	}
+	defer rows.Close()
 
-	// Resolve parent/child relationships
-	for _, bead := range graph.Beads {
-		if bead.ParentID != "" {
-			if parent, ok := graph.Beads[bead.ParentID]; ok {
-				bead.Parent = parent
-				parent.Children = append(parent.Children, bead)
-			}
-		} else {
-			graph.RootBeads = append(graph.RootBeads, bead)
+	for rows.Next() {
+		var (
+			comment   Comment
+			createdAt string
+		)
+		if err := rows.Scan(&comment.ID, &comment.IssueID, &comment.Author, &comment.Text, &createdAt); err != nil {
+			result.Errors = append(result.Errors, &ParseError{
+				Message: "failed to scan comment",
+				Err:     err,
+			})
+			continue
+		}
+		if t, err := parseTime(createdAt); err == nil {
+			comment.CreatedAt = t
 		}
-	}
 
-	// Resolve blocker/blocked relationships
-	for _, bead := range graph.Beads {
-		for _, blockerID := range bead.BlockerIDs {
-			if blocker, ok := graph.Beads[blockerID]; ok {
-				bead.Blockers = append(bead.Blockers, blocker)
-				blocker.Blocked = append(blocker.Blocked, bead)
-				blocker.BlockedIDs = append(blocker.BlockedIDs, bead.ID)
-			}
+		if bead, ok := beadMap[comment.IssueID]; ok {
+			c := comment
+			bead.Comments = append(bead.Comments, &c)
 		}
 	}
+}
 
-	// Build indices
-	graph.rebuildIndices()
-
-	return graph, nil
+// BuildGraphFromSQLite builds a graph from a SQLite database
+func BuildGraphFromSQLite(dbPath string) (*BeadsGraph, error) {
+	return BuildGraphFromSource(NewSQLiteSource(dbPath))
 }
@@ -0,0 +1,126 @@
+package beads
+
+import (
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeSQLite creates a database from the given statements
+func writeSQLite(t *testing.T, statements ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "beads.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+	return path
+}
+
+func TestParseSQLiteSchemas(t *testing.T) {
+	tests := []struct {
+		name       string
+		statements []string
+	}{
+		{"bd schema", []string{
+			`CREATE TABLE issues (id TEXT, title TEXT, status TEXT, issue_type TEXT, priority INT, created_at TEXT, updated_at TEXT)`,
+			`INSERT INTO issues VALUES ('bd-1', 'one', 'open', 'task', 1, '2026-01-01T00:00:00Z', '2026-01-02T00:00:00Z'),
+				('bd-2', 'two', 'open', 'bug', 2, '2026-01-01T00:00:00Z', '2026-01-02T00:00:00Z'),
+				('bd-3', 'gone', 'tombstone', 'task', 2, '2026-01-01T00:00:00Z', '2026-01-02T00:00:00Z')`,
+			`CREATE TABLE labels (issue_id TEXT, label TEXT)`,
+			`INSERT INTO labels VALUES ('bd-1', 'ui'), ('bd-1', 'urgent')`,
+			`CREATE TABLE dependencies (issue_id TEXT, depends_on_id TEXT, type TEXT, created_at TEXT, created_by TEXT)`,
+			`INSERT INTO dependencies VALUES ('bd-1', 'bd-2', 'blocks', '2026-01-03T00:00:00Z', 'sam')`,
+			`CREATE TABLE comments (id INTEGER, issue_id TEXT, author TEXT, text TEXT, created_at TEXT)`,
+			`INSERT INTO comments VALUES (2, 'bd-1', 'sam', 'second', '2026-01-05T00:00:00Z'), (1, 'bd-1', 'kim', 'first', '2026-01-04T00:00:00Z')`,
+		}},
+		{"alternative column names", []string{
+			`CREATE TABLE beads (bead_id TEXT, name TEXT, state TEXT, kind TEXT, created TEXT, updated TEXT)`,
+			`INSERT INTO beads VALUES ('bd-1', 'one', 'open', 'task', '2026-01-01 00:00:00', '2026-01-02'),
+				('bd-2', 'two', 'open', 'bug', '2026-01-01', '2026-01-02')`,
+			`CREATE TABLE issue_labels (bead_id TEXT, name TEXT)`,
+			`INSERT INTO issue_labels VALUES ('bd-1', 'ui'), ('bd-1', 'urgent')`,
+			`CREATE TABLE dependencies (bead_id TEXT, blocker_id TEXT)`,
+			`INSERT INTO dependencies VALUES ('bd-1', 'bd-2')`,
+			`CREATE TABLE comments (bead_id TEXT, created_by TEXT, body TEXT, created TEXT)`,
+			`INSERT INTO comments VALUES ('bd-1', 'kim', 'first', '2026-01-04'), ('bd-1', 'sam', 'second', '2026-01-05')`,
+		}},
+		{"labels table without label columns", []string{
+			`CREATE TABLE issues (id TEXT, title TEXT, status TEXT)`,
+			`INSERT INTO issues VALUES ('bd-1', 'one', 'open'), ('bd-2', 'two', 'open')`,
+			`CREATE TABLE labels (id INTEGER, color TEXT)`,
+			`INSERT INTO labels VALUES (1, 'red')`,
+			`CREATE TABLE issue_labels (issue_id TEXT, label TEXT)`,
+			`INSERT INTO issue_labels VALUES ('bd-1', 'ui'), ('bd-1', 'urgent')`,
+			`CREATE TABLE dependencies (issue_id TEXT, depends_on TEXT, dep_type TEXT)`,
+			`INSERT INTO dependencies VALUES ('bd-1', 'bd-2', 'blocks')`,
+			`CREATE TABLE comments (issue_id TEXT, author TEXT, content TEXT)`,
+			`INSERT INTO comments VALUES ('bd-1', 'kim', 'first'), ('bd-1', 'sam', 'second')`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseSQLite(writeSQLite(t, tt.statements...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Errors) != 0 {
+				t.Fatalf("errors: %v", result.Errors)
+			}
+			beads := make(map[string]*Bead)
+			for _, bead := range result.Beads {
+				beads[bead.ID] = bead
+			}
+			if len(beads) != 2 {
+				t.Fatalf("loaded %d beads, want 2 (tombstones skipped)", len(beads))
+			}
+
+			one := beads["bd-1"]
+			if one.Title != "one" || one.Status != StatusOpen {
+				t.Errorf("bd-1 = %q %q", one.Title, one.Status)
+			}
+			if !slices.Equal(one.Labels, []string{"ui", "urgent"}) {
+				t.Errorf("labels = %v", one.Labels)
+			}
+			if len(one.Dependencies) != 1 || one.Dependencies[0].DependsOnID != "bd-2" || one.Dependencies[0].Type != "blocks" {
+				t.Fatalf("dependencies = %+v", one.Dependencies)
+			}
+			if !slices.Equal(one.BlockerIDs, []string{"bd-2"}) {
+				t.Errorf("blockers = %v", one.BlockerIDs)
+			}
+			if len(one.Comments) != 2 || one.Comments[0].Text != "first" || one.Comments[1].Author != "sam" {
+				t.Errorf("comments = %+v", one.Comments)
+			}
+			if len(beads["bd-2"].Labels) != 0 || len(beads["bd-2"].Dependencies) != 0 {
+				t.Errorf("bd-2 has labels %v, dependencies %v", beads["bd-2"].Labels, beads["bd-2"].Dependencies)
+			}
+		})
+	}
+}
+
+func TestParseSQLiteWithoutSideTables(t *testing.T) {
+	result, err := ParseSQLite(writeSQLite(t,
+		`CREATE TABLE issues (id TEXT, title TEXT)`,
+		`INSERT INTO issues VALUES ('bd-1', 'one')`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Beads) != 1 || len(result.Errors) != 0 {
+		t.Fatalf("beads = %d, errors = %v", len(result.Beads), result.Errors)
+	}
+	bead := result.Beads[0]
+	if bead.Status != StatusOpen || bead.Type != "task" || bead.Priority != 2 {
+		t.Errorf("defaults = %q %q %d", bead.Status, bead.Type, bead.Priority)
+	}
+	if bead.Labels != nil || bead.Dependencies != nil || bead.Comments != nil {
+		t.Errorf("side data without side tables: %v %v %v", bead.Labels, bead.Dependencies, bead.Comments)
+	}
+}
@@ -19,6 +19,16 @@ const (
 	StatusHooked     Status = "hooked"
 )
 
+// IsKnown returns true if the status is one bd defines
+func (s Status) IsKnown() bool {
+	switch s {
+	case StatusOpen, StatusInProgress, StatusBlocked, StatusDeferred,
+		StatusClosed, StatusTombstone, StatusPinned, StatusHooked:
+		return true
+	}
+	return false
+}
+
 // BeadType represents the type of issue
 type BeadType string
 
@@ -35,6 +45,16 @@ const (
 	TypeEvent        BeadType = "event"
 )
 
+// IsKnown returns true if the type is one bd defines
+func (t BeadType) IsKnown() bool {
+	switch t {
+	case TypeTask, TypeBug, TypeFeature, TypeEpic, TypeChore, TypeMessage,
+		TypeMergeRequest, TypeMolecule, TypeGate, TypeEvent:
+		return true
+	}
+	return false
+}
+
 // DependencyType represents the type of relationship between issues
 type DependencyType string
 
@@ -131,6 +151,13 @@ type Bead struct {
 	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
 	DeletedBy    string     `json:"deleted_by,omitempty"`
 	DeleteReason string     `json:"delete_reason,omitempty"`
+
+	// Provenance
+	SourceLine int    `json:"-"`                    // Line in the JSONL file this record was read from (0 for SQLite)
+	Conflicted bool   `json:"conflicted,omitempty"` // Read from inside an unresolved git merge conflict
+	Project    string `json:"project,omitempty"`    // Project the bead came from, in an aggregate graph
+
+	fingerprint uint64 // Hash of the JSONL record, so unchanged records can be skipped when diffing; 0 if unknown
 }
 
 // SetDefaults applies default values for fields omitted during parsing
@@ -165,6 +192,22 @@ func (b *Bead) IsTombstone() bool {
 	return b.Status == StatusTombstone
 }
 
+// IsDescendantOf returns true if the bead is a child, grandchild, ... of id.
+// Ancestors are looked up in beads, the map of the snapshot the bead belongs
+// to: beyond one hop, Parent pointers may lead to earlier versions.
+func (b *Bead) IsDescendantOf(id string, beads map[string]*Bead) bool {
+	// SQLite parents are arbitrary, so guard against cycles
+	for bead, hops := b, 0; bead != nil && hops <= len(beads); bead, hops = beads[bead.ParentID], hops+1 {
+		if bead.ParentID == "" {
+			return false
+		}
+		if bead.ParentID == id {
+			return true
+		}
+	}
+	return false
+}
+
 // AuditEntry represents a history event (for future timeline support)
 type AuditEntry struct {
 	Timestamp time.Time `json:"timestamp"`
@@ -1,37 +1,52 @@
 package beads
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 )
 
-// Watcher watches the beads.jsonl file for changes
+// Watcher watches the graph's data source files for changes
 type Watcher struct {
-	graph      *BeadsGraph
-	fsWatcher  *fsnotify.Watcher
-	filePath   string
-	fileName   string // Just the filename (e.g., "issues.jsonl")
-	dirPath    string // Parent directory to watch
-	debounce   time.Duration
-	agentMode  bool
-	onChange   func()
-	stopCh     chan struct{}
-	wg         sync.WaitGroup
-	mu         sync.Mutex
+	graph       *BeadsGraph
+	fsWatcher   *fsnotify.Watcher
+	targets     map[string]bool // Cleaned paths of the files we react to
+	dirPaths    []string        // Parent directories to watch
+	debounce    time.Duration
+	agentMode   bool
+	onChange    func()
+	onDegraded  func(*DegradedError)
+	onRecovered func()
+	attempts    int // Consecutive rejected reloads, only touched by watch()
+	errors      atomic.Uint64
+	stopCh      chan struct{}
+	wg          sync.WaitGroup
+	mu          sync.Mutex
 }
 
 // WatcherConfig configures the file watcher
 type WatcherConfig struct {
-	FilePath   string
-	Graph      *BeadsGraph
-	AgentMode  bool
-	OnChange   func() // Callback when data changes
+	Graph       *BeadsGraph // Files to watch come from Graph.Source
+	AgentMode   bool
+	OnChange    func()               // Callback when data changes
+	OnDegraded  func(*DegradedError) // Callback when a reload is rejected and old data is kept
+	OnRecovered func()               // Callback when a reload succeeds after being degraded
 }
 
+// Reload retry back-off. After maxReloadRetries rejected attempts the file is
+// treated as intentionally changed and loaded as-is.
+const (
+	reloadRetryBase  = 500 * time.Millisecond
+	reloadRetryMax   = 30 * time.Second
+	maxReloadRetries = 5
+)
+
 // NewWatcher creates a new file watcher
 func NewWatcher(config WatcherConfig) (*Watcher, error) {
 	fsWatcher, err := fsnotify.NewWatcher()
@@ -44,29 +59,48 @@ func NewWatcher(config WatcherConfig) (*Watcher, error) {
 		debounce = 2 * time.Second
 	}
 
+	if config.Graph == nil || config.Graph.Source == nil {
+		fsWatcher.Close()
+		return nil, fmt.Errorf("watcher requires a graph with a data source")
+	}
+
+	targets := make(map[string]bool)
+	var dirPaths []string
+	seenDirs := make(map[string]bool)
+	for _, target := range config.Graph.Source.WatchTargets() {
+		targets[filepath.Clean(target)] = true
+		dir := filepath.Dir(target)
+		if !seenDirs[dir] {
+			seenDirs[dir] = true
+			dirPaths = append(dirPaths, dir)
+		}
+	}
+
 	w := &Watcher{
-		graph:     config.Graph,
-		fsWatcher: fsWatcher,
-		filePath:  config.FilePath,
-		fileName:  filepath.Base(config.FilePath),
-		dirPath:   filepath.Dir(config.FilePath),
-		debounce:  debounce,
-		agentMode: config.AgentMode,
-		onChange:  config.OnChange,
-		stopCh:    make(chan struct{}),
+		graph:       config.Graph,
+		fsWatcher:   fsWatcher,
+		targets:     targets,
+		dirPaths:    dirPaths,
+		debounce:    debounce,
+		agentMode:   config.AgentMode,
+		onChange:    config.OnChange,
+		onDegraded:  config.OnDegraded,
+		onRecovered: config.OnRecovered,
+		stopCh:      make(chan struct{}),
 	}
 
 	return w, nil
 }
 
-// Start begins watching the file
+// Start begins watching the data source files
 func (w *Watcher) Start() error {
-	// Watch the directory instead of the file to catch atomic writes (rename)
-	err := w.fsWatcher.Add(w.dirPath)
-	if err != nil {
-		return err
+	// Watch the directories instead of the files to catch atomic writes (rename)
+	for _, dir := range w.dirPaths {
+		if err := w.fsWatcher.Add(dir); err != nil {
+			return err
+		}
+		log.Printf("Watching directory: %s for changes to %s", dir, w.graph.Source.Describe())
 	}
-	log.Printf("Watching directory: %s for changes to %s", w.dirPath, w.fileName)
 
 	w.wg.Add(1)
 	go w.watch()
@@ -93,11 +127,24 @@ func (w *Watcher) SetAgentMode(enabled bool) {
 	}
 }
 
+// AgentMode reports whether agent mode is on
+func (w *Watcher) AgentMode() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.agentMode
+}
+
+// Errors returns how many errors the file system watcher has reported
+func (w *Watcher) Errors() uint64 {
+	return w.errors.Load()
+}
+
 func (w *Watcher) watch() {
 	defer w.wg.Done()
 
 	var timer *time.Timer
 	var timerCh <-chan time.Time
+	var retryCh <-chan time.Time
 
 	for {
 		select {
@@ -112,9 +159,8 @@ func (w *Watcher) watch() {
 				return
 			}
 
-			// Only process events for our target file
-			eventFileName := filepath.Base(event.Name)
-			if eventFileName != w.fileName {
+			// Only process events for our target files
+			if !w.targets[filepath.Clean(event.Name)] {
 				continue
 			}
 
@@ -126,7 +172,7 @@ func (w *Watcher) watch() {
 			if !isRelevant {
 				continue
 			}
-			
+
 			log.Printf("File event: %s %s", event.Op, event.Name)
 
 			// Debounce: reset timer on each event
@@ -142,21 +188,77 @@ func (w *Watcher) watch() {
 
 		case <-timerCh:
 			// Debounce period elapsed, reload the graph
-			log.Println("File changed, reloading graph...")
-			if err := w.graph.Rebuild(); err != nil {
-				log.Printf("Error rebuilding graph: %v", err)
-				continue
-			}
+			retryCh = w.reloadAndNotify()
 
-			if w.onChange != nil {
-				w.onChange()
-			}
+		case <-retryCh:
+			log.Println("Retrying rejected reload...")
+			retryCh = w.reloadAndNotify()
 
 		case err, ok := <-w.fsWatcher.Errors:
 			if !ok {
 				return
 			}
+			w.errors.Add(1)
 			log.Printf("Watcher error: %v", err)
 		}
 	}
 }
+
+// reloadAndNotify reloads the graph and fires the matching callback.
+// If the reload was rejected it returns a channel for the next retry.
+func (w *Watcher) reloadAndNotify() <-chan time.Time {
+	err := w.reload()
+
+	var degraded *DegradedError
+	if errors.As(err, &degraded) {
+		w.attempts = degraded.Attempts
+		if degraded.Attempts <= maxReloadRetries {
+			delay := reloadRetryBase << (degraded.Attempts - 1)
+			if delay > reloadRetryMax {
+				delay = reloadRetryMax
+			}
+			log.Printf("%v (retrying in %s)", degraded, delay)
+			if w.onDegraded != nil {
+				w.onDegraded(degraded)
+			}
+			return time.After(delay)
+		}
+
+		// The file has looked like this for a while, so it's not a half-finished write
+		log.Printf("%v; loading it anyway", degraded)
+		err = w.graph.ForceRebuild()
+	}
+	if err != nil {
+		log.Printf("Error rebuilding graph: %v", err)
+		return nil
+	}
+
+	if w.attempts > 0 && w.onRecovered != nil {
+		w.onRecovered()
+	}
+	w.attempts = 0
+
+	if w.onChange != nil {
+		w.onChange()
+	}
+	return nil
+}
+
+// reload merges just the new tail when the data file was only appended to,
+// and falls back to a full rebuild for any other kind of change.
+// While degraded the append-tracking state may not match the served graph,
+// so only full rebuilds are attempted.
+func (w *Watcher) reload() error {
+	if w.graph.Degraded() != nil {
+		log.Println("File changed, reloading graph...")
+		return w.graph.Rebuild()
+	}
+
+	if src, ok := w.graph.Source.(AppendSource); ok && src.Appended() {
+		log.Println("File appended, merging new beads...")
+		return w.graph.LoadAppended()
+	}
+
+	log.Println("File changed, reloading graph...")
+	return w.graph.Rebuild()
+}
@@ -0,0 +1,79 @@
+package beads
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcherReloadsSQLiteFromWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "beads.db")
+	// One connection that stays open, with automatic checkpoints off, so
+	// commits stay in the -wal file like bd's do between checkpoints
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	for _, statement := range []string{
+		`PRAGMA journal_mode=WAL`,
+		`PRAGMA wal_autocheckpoint=0`,
+		`CREATE TABLE issues (id TEXT, title TEXT, status TEXT, created_at TEXT, updated_at TEXT)`,
+		`INSERT INTO issues VALUES ('bd-1', 'one', 'open', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`,
+		`PRAGMA wal_checkpoint(TRUNCATE)`,
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+
+	graph, err := BuildGraphFromSource(NewSQLiteSource(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := make(chan struct{}, 1)
+	watcher, err := NewWatcher(WatcherConfig{
+		Graph: graph,
+		OnChange: func() {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := watcher.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Stop()
+
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO issues VALUES ('bd-2', 'two', 'open', '2026-01-02T00:00:00Z', '2026-01-02T00:00:00Z')`); err != nil {
+		t.Fatal(err)
+	}
+	if after, err := os.ReadFile(path); err != nil || !bytes.Equal(before, after) {
+		t.Fatalf("the insert changed the main database file (%v), not only the WAL", err)
+	}
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after a commit to the WAL")
+	}
+	snap := graph.Snapshot()
+	if snap.Generation != 2 || snap.GetBead("bd-2") == nil {
+		t.Errorf("generation %d, bd-2 = %v after the reload", snap.Generation, snap.GetBead("bd-2"))
+	}
+	if len(snap.Changes) != 1 || snap.Changes[0].Type != ChangeCreated {
+		t.Errorf("changes = %v, want bd-2 created", snap.Changes)
+	}
+}
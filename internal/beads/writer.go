@@ -0,0 +1,626 @@
+package beads
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors returned by writes
+var (
+	ErrReadOnly      = errors.New("data source is read-only")
+	ErrBeadNotFound  = errors.New("bead not found")
+	ErrMergeConflict = errors.New("data file has unresolved merge conflicts")
+)
+
+// ValidationError reports an invalid value in a write request
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Message)
+}
+
+// WritableSource is implemented by sources the API can write changes to
+type WritableSource interface {
+	DataSource
+
+	// Update locks the data, hands it to fn as a transaction, and writes the
+	// result back atomically if fn returns nil. Nothing is written otherwise.
+	Update(fn func(tx *Tx) error) error
+}
+
+// Tx is an in-progress change to a JSONL file. Records are edited at the JSON
+// object level, so fields seeBeads doesn't know about survive the rewrite.
+type Tx struct {
+	lines   [][]byte
+	index   map[string]int // ID -> line holding the winning record
+	beads   map[string]*Bead
+	prefix  string // Fallback ID prefix when the file has no beads yet
+	now     time.Time
+	changed bool
+}
+
+// DefaultActor is recorded as the author of changes made without a name
+const DefaultActor = "seebeads"
+
+// Update implements WritableSource. The file is replaced with a temp file and
+// a rename, so bd never sees a half-written file, while holding an advisory
+// lock that keeps other seeBeads instances, whoever runs them, from
+// interleaving their edits.
+func (s *JSONLSource) Update(fn func(tx *Tx) error) error {
+	lock, err := lockPath(s.Path)
+	if err != nil {
+		return fmt.Errorf("failed to lock %s: %w", filepath.Base(s.Path), err)
+	}
+	unlock, err := lockFile(lock)
+	if err != nil {
+		return fmt.Errorf("failed to lock %s: %w", filepath.Base(s.Path), err)
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(s.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	tx, err := newTx(data, defaultPrefix(s.Path))
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if !tx.changed {
+		return nil
+	}
+
+	// Keep the original file's permissions
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(s.Path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	return WriteFileAtomic(s.Path, tx.bytes(), mode)
+}
+
+// PrivateDirName is the directory in .beads holding seeBeads' own files, like
+// write locks and the development certificate. It ignores itself in git, so
+// nothing in it is ever committed.
+const PrivateDirName = ".seebeads"
+
+// PrivateDir returns the private directory inside a .beads directory,
+// creating it and its .gitignore if needed
+func PrivateDir(beadsDir string) (string, error) {
+	dir := filepath.Join(beadsDir, PrivateDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	ignore := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		if err := os.WriteFile(ignore, []byte("# Created by seebeads\n*\n"), 0644); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// lockPath returns the lock file guarding writes to a data file, in the
+// private directory beside it. The data file itself can't be locked, since
+// every write replaces it with a new file.
+func lockPath(path string) (string, error) {
+	dir, err := PrivateDir(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(path)+".lock"), nil
+}
+
+// defaultPrefix is bd's default issue prefix: the project directory's name
+func defaultPrefix(path string) string {
+	return filepath.Base(filepath.Dir(filepath.Dir(path)))
+}
+
+// newTx indexes the records in a JSONL file. Files with unresolved merge
+// conflicts are refused, since it's unclear which side an edit belongs to.
+func newTx(data []byte, prefix string) (*Tx, error) {
+	tx := &Tx{
+		index:  make(map[string]int),
+		beads:  make(map[string]*Bead),
+		prefix: prefix,
+		now:    time.Now().UTC(),
+	}
+
+	if len(data) > 0 {
+		tx.lines = bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	}
+
+	for i, raw := range tx.lines {
+		line := strings.TrimSpace(string(raw))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, conflictStartMarker) {
+			return nil, ErrMergeConflict
+		}
+		bead, err := parseLine(line)
+		if err != nil {
+			continue
+		}
+		bead.SetDefaults()
+
+		// Same rule as duplicate resolution when loading
+		if current, ok := tx.beads[bead.ID]; ok && !supersedes(bead, current) {
+			continue
+		}
+		tx.beads[bead.ID] = bead
+		tx.index[bead.ID] = i
+	}
+	return tx, nil
+}
+
+// bytes returns the file contents with one record per line
+func (tx *Tx) bytes() []byte {
+	var buf bytes.Buffer
+	for _, line := range tx.lines {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// Get returns the current version of a bead. Deleted beads are not found.
+func (tx *Tx) Get(id string) (*Bead, error) {
+	bead, ok := tx.beads[id]
+	if !ok || bead.IsTombstone() {
+		return nil, fmt.Errorf("%w: %s", ErrBeadNotFound, id)
+	}
+	return bead, nil
+}
+
+// NewBead describes a bead to create. ID is generated when empty; with Parent
+// set it becomes the parent's next hierarchical child ID (bd-a1b2.3).
+type NewBead struct {
+	ID                 string   `json:"id,omitempty"`
+	Parent             string   `json:"parent,omitempty"`
+	Title              string   `json:"title"`
+	Description        string   `json:"description,omitempty"`
+	Design             string   `json:"design,omitempty"`
+	AcceptanceCriteria string   `json:"acceptance_criteria,omitempty"`
+	Notes              string   `json:"notes,omitempty"`
+	Status             Status   `json:"status,omitempty"`
+	Priority           *int     `json:"priority,omitempty"`
+	Type               BeadType `json:"issue_type,omitempty"`
+	Assignee           string   `json:"assignee,omitempty"`
+	Labels             []string `json:"labels,omitempty"`
+	CreatedBy          string   `json:"created_by,omitempty"`
+}
+
+// Create adds a new bead at the end of the file
+func (tx *Tx) Create(input *NewBead) (*Bead, error) {
+	bead := &Bead{
+		ID:                 input.ID,
+		Title:              input.Title,
+		Description:        input.Description,
+		Design:             input.Design,
+		AcceptanceCriteria: input.AcceptanceCriteria,
+		Notes:              input.Notes,
+		Status:             input.Status,
+		Priority:           2,
+		Type:               input.Type,
+		Assignee:           input.Assignee,
+		Labels:             input.Labels,
+		CreatedAt:          tx.now,
+		CreatedBy:          input.CreatedBy,
+		UpdatedAt:          tx.now,
+	}
+	if input.Priority != nil {
+		bead.Priority = *input.Priority
+	}
+	if bead.CreatedBy == "" {
+		bead.CreatedBy = DefaultActor
+	}
+	bead.SetDefaults()
+	if bead.Status == StatusClosed {
+		bead.ClosedAt = &tx.now
+	}
+
+	switch {
+	case bead.ID != "" && input.Parent != "":
+		return nil, &ValidationError{"id", "can't set both id and parent"}
+	case bead.ID != "":
+		if _, exists := tx.beads[bead.ID]; exists {
+			return nil, &ValidationError{"id", bead.ID + " already exists"}
+		}
+	case input.Parent != "":
+		if _, err := tx.Get(input.Parent); err != nil {
+			return nil, err
+		}
+		bead.ID = tx.childID(input.Parent)
+	default:
+		id, err := tx.newID()
+		if err != nil {
+			return nil, err
+		}
+		bead.ID = id
+	}
+
+	if err := validateBead(bead); err != nil {
+		return nil, err
+	}
+
+	record := make(map[string]json.RawMessage)
+	for name := range recordFields {
+		if err := setRecordField(record, bead, name); err != nil {
+			return nil, err
+		}
+	}
+	return tx.put(bead.ID, -1, record)
+}
+
+// BeadPatch lists the fields to change on a bead; nil fields are left alone
+type BeadPatch struct {
+	Title              *string   `json:"title,omitempty"`
+	Description        *string   `json:"description,omitempty"`
+	Design             *string   `json:"design,omitempty"`
+	AcceptanceCriteria *string   `json:"acceptance_criteria,omitempty"`
+	Notes              *string   `json:"notes,omitempty"`
+	Status             *Status   `json:"status,omitempty"`
+	Priority           *int      `json:"priority,omitempty"`
+	Type               *BeadType `json:"issue_type,omitempty"`
+	Assignee           *string   `json:"assignee,omitempty"`
+	Labels             *[]string `json:"labels,omitempty"`
+	CloseReason        *string   `json:"close_reason,omitempty"`
+	EstimatedMinutes   *int      `json:"estimated_minutes,omitempty"`
+
+	// Applied after Labels, for editing many beads' labels at once
+	AddLabels    []string `json:"add_labels,omitempty"`
+	RemoveLabels []string `json:"remove_labels,omitempty"`
+}
+
+// apply changes bead in place and returns the JSON names of the fields set
+func (p *BeadPatch) apply(bead *Bead, now time.Time) []string {
+	var fields []string
+	setString := func(name string, dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+			fields = append(fields, name)
+		}
+	}
+	setString("title", &bead.Title, p.Title)
+	setString("description", &bead.Description, p.Description)
+	setString("design", &bead.Design, p.Design)
+	setString("acceptance_criteria", &bead.AcceptanceCriteria, p.AcceptanceCriteria)
+	setString("notes", &bead.Notes, p.Notes)
+	setString("assignee", &bead.Assignee, p.Assignee)
+	setString("close_reason", &bead.CloseReason, p.CloseReason)
+
+	if p.Priority != nil {
+		bead.Priority = *p.Priority
+		fields = append(fields, "priority")
+	}
+	if p.Type != nil {
+		bead.Type = *p.Type
+		fields = append(fields, "issue_type")
+	}
+	if p.Labels != nil || len(p.AddLabels) > 0 || len(p.RemoveLabels) > 0 {
+		labels := bead.Labels
+		if p.Labels != nil {
+			labels = *p.Labels
+		}
+		bead.Labels = editLabels(labels, p.AddLabels, p.RemoveLabels)
+		fields = append(fields, "labels")
+	}
+	if p.EstimatedMinutes != nil {
+		minutes := *p.EstimatedMinutes
+		bead.EstimatedMinutes = &minutes
+		fields = append(fields, "estimated_minutes")
+	}
+
+	// Closing stamps closed_at; reopening clears it along with the reason
+	if p.Status != nil && *p.Status != bead.Status {
+		wasClosed := bead.Status == StatusClosed
+		bead.Status = *p.Status
+		fields = append(fields, "status")
+		switch {
+		case bead.Status == StatusClosed:
+			bead.ClosedAt = &now
+			fields = append(fields, "closed_at")
+		case wasClosed:
+			bead.ClosedAt = nil
+			fields = append(fields, "closed_at")
+			if p.CloseReason == nil {
+				bead.CloseReason = ""
+				fields = append(fields, "close_reason")
+			}
+		}
+	}
+	return fields
+}
+
+// editLabels returns a new label list with add appended and remove dropped.
+// Labels already present aren't added twice.
+func editLabels(labels, add, remove []string) []string {
+	result := make([]string, 0, len(labels)+len(add))
+	for _, label := range append(labels[:len(labels):len(labels)], add...) {
+		if !containsString(result, label) && !containsString(remove, label) {
+			result = append(result, label)
+		}
+	}
+	return result
+}
+
+// Update applies a patch to an existing bead. If nothing would change, the
+// record is left alone and the current bead is returned.
+func (tx *Tx) Update(id string, patch *BeadPatch) (*Bead, error) {
+	current, err := tx.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	bead := *current
+	fields := patch.apply(&bead, tx.now)
+	if len(ChangedFields(current, &bead)) == 0 {
+		return current, nil
+	}
+	if err := validateBead(&bead); err != nil {
+		return nil, err
+	}
+	return tx.edit(&bead, fields)
+}
+
+// AddComment appends a comment to a bead
+func (tx *Tx) AddComment(id, author, text string) (*Comment, error) {
+	current, err := tx.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, &ValidationError{"text", "comment can't be empty"}
+	}
+	if author == "" {
+		author = DefaultActor
+	}
+
+	var nextID int64 = 1
+	for _, comment := range current.Comments {
+		if comment.ID >= nextID {
+			nextID = comment.ID + 1
+		}
+	}
+	comment := &Comment{
+		ID:        nextID,
+		IssueID:   id,
+		Author:    author,
+		Text:      text,
+		CreatedAt: tx.now,
+	}
+
+	bead := *current
+	bead.Comments = append(current.Comments[:len(current.Comments):len(current.Comments)], comment)
+	if _, err := tx.edit(&bead, []string{"comments"}); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// edit rewrites the given fields of a bead's winning record and bumps updated_at
+func (tx *Tx) edit(bead *Bead, fields []string) (*Bead, error) {
+	line := tx.index[bead.ID]
+	record := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(bytes.TrimSpace(tx.lines[line]), &record); err != nil {
+		return nil, err
+	}
+
+	bead.UpdatedAt = tx.now
+	for _, name := range append(fields, "updated_at") {
+		if err := setRecordField(record, bead, name); err != nil {
+			return nil, err
+		}
+	}
+	return tx.put(bead.ID, line, record)
+}
+
+// put stores a record at line, or appends it if line is -1, and returns the
+// bead as the parser will read it back
+func (tx *Tx) put(id string, line int, record map[string]json.RawMessage) (*Bead, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	bead, err := parseLine(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("record for %s doesn't round-trip: %w", id, err)
+	}
+	bead.SetDefaults()
+
+	if line < 0 {
+		tx.lines = append(tx.lines, data)
+		line = len(tx.lines) - 1
+	} else {
+		tx.lines[line] = data
+	}
+	tx.index[id] = line
+	tx.beads[id] = bead
+	tx.changed = true
+	return bead, nil
+}
+
+// childID returns the next unused hierarchical ID under parent
+func (tx *Tx) childID(parent string) string {
+	next := 1
+	for id := range tx.beads {
+		if extractParentID(id) != parent {
+			continue
+		}
+		if n, err := strconv.Atoi(id[len(parent)+1:]); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+	return fmt.Sprintf("%s.%d", parent, next)
+}
+
+// newID generates a bd-style hash ID ("<prefix>-<base36>") using the prefix
+// most beads in the file share. IDs get longer if short ones keep colliding.
+func (tx *Tx) newID() (string, error) {
+	prefix := tx.commonPrefix()
+	for length := 4; length <= 8; length++ {
+		for attempt := 0; attempt < 10; attempt++ {
+			suffix, err := randomBase36(length)
+			if err != nil {
+				return "", err
+			}
+			id := prefix + "-" + suffix
+			if _, exists := tx.beads[id]; !exists {
+				return id, nil
+			}
+		}
+	}
+	return "", errors.New("could not generate a unique bead ID")
+}
+
+// commonPrefix returns the most common ID prefix in the file
+func (tx *Tx) commonPrefix() string {
+	counts := make(map[string]int)
+	for id := range tx.beads {
+		root, _, _ := strings.Cut(id, ".")
+		if dash := strings.LastIndex(root, "-"); dash > 0 {
+			counts[root[:dash]]++
+		}
+	}
+
+	best, bestCount := tx.prefix, 0
+	for prefix, count := range counts {
+		if count > bestCount || (count == bestCount && prefix < best) {
+			best, bestCount = prefix, count
+		}
+	}
+	return best
+}
+
+func randomBase36(length int) (string, error) {
+	const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+	buf := make([]byte, length)
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(digits))))
+		if err != nil {
+			return "", err
+		}
+		buf[i] = digits[n.Int64()]
+	}
+	return string(buf), nil
+}
+
+// validateBead checks the fields a write can set
+func validateBead(bead *Bead) error {
+	if strings.TrimSpace(bead.Title) == "" {
+		return &ValidationError{"title", "title is required"}
+	}
+	if !bead.Status.IsKnown() || bead.Status == StatusTombstone {
+		return &ValidationError{"status", fmt.Sprintf("unknown status %q", bead.Status)}
+	}
+	if !bead.Type.IsKnown() {
+		return &ValidationError{"issue_type", fmt.Sprintf("unknown type %q", bead.Type)}
+	}
+	if bead.Priority < 0 || bead.Priority > 4 {
+		return &ValidationError{"priority", "priority must be between 0 and 4"}
+	}
+	if strings.ContainsAny(bead.ID, " \t\r\n") {
+		return &ValidationError{"id", "id can't contain whitespace"}
+	}
+	return nil
+}
+
+// recordField is a Bead field written to the data file
+type recordField struct {
+	index     int
+	omitEmpty bool
+}
+
+// recordFields maps JSON names to the Bead fields bd stores. Computed fields
+// and API-only fields like "conflicted" and "project" are never written.
+var recordFields = func() map[string]recordField {
+	fields := make(map[string]recordField)
+	t := reflect.TypeOf(Bead{})
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := t.Field(i).Tag.Lookup("json")
+		if !ok {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "-" || name == "conflicted" || name == "project" {
+			continue
+		}
+		fields[name] = recordField{index: i, omitEmpty: strings.Contains(options, "omitempty")}
+	}
+	return fields
+}()
+
+// setRecordField copies one field from bead into a raw JSON record,
+// dropping it if it's empty and bd omits empty values
+func setRecordField(record map[string]json.RawMessage, bead *Bead, name string) error {
+	field, ok := recordFields[name]
+	if !ok {
+		return fmt.Errorf("unknown record field %q", name)
+	}
+
+	value := reflect.ValueOf(bead).Elem().Field(field.index)
+	if field.omitEmpty && isEmptyValue(value) {
+		delete(record, name)
+		return nil
+	}
+	data, err := json.Marshal(value.Interface())
+	if err != nil {
+		return err
+	}
+	record[name] = data
+	return nil
+}
+
+// isEmptyValue matches encoding/json's omitempty rule
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+// WriteFileAtomic replaces path with data via a temp file and rename, so
+// readers never see a partly written file
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
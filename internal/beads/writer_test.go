@@ -0,0 +1,321 @@
+package beads
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpdateLockIsIgnoredByGit(t *testing.T) {
+	path := writeJSONL(t, record("bd-1", "one", 1))
+
+	src := NewJSONLSource(path)
+	title := "two"
+	err := src.Update(func(tx *Tx) error {
+		_, err := tx.Update("bd-1", &BeadPatch{Title: &title})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if name := entry.Name(); name != "issues.jsonl" && name != PrivateDirName {
+			t.Errorf("left %s next to the data file", name)
+		}
+	}
+
+	lock, err := lockPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(lock); err != nil {
+		t.Errorf("lock file: %v", err)
+	}
+	ignore, err := os.ReadFile(filepath.Join(filepath.Dir(lock), ".gitignore"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(ignore), "\n*\n") {
+		t.Errorf("lock directory doesn't ignore itself: %q", ignore)
+	}
+}
+
+func TestUpdateWaitsForTheLock(t *testing.T) {
+	path := writeJSONL(t, record("bd-1", "one", 1))
+	first := NewJSONLSource(path)
+	second := NewJSONLSource(path)
+
+	entered := make(chan string)
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- first.Update(func(tx *Tx) error {
+			title := "first"
+			entered <- "first"
+			<-release
+			_, err := tx.Update("bd-1", &BeadPatch{Title: &title})
+			return err
+		})
+	}()
+	if got := <-entered; got != "first" {
+		t.Fatalf("%s entered first", got)
+	}
+
+	go func() {
+		done <- second.Update(func(tx *Tx) error {
+			entered <- tx.beads["bd-1"].Title
+			title := "second"
+			_, err := tx.Update("bd-1", &BeadPatch{Title: &title})
+			return err
+		})
+	}()
+	select {
+	case <-entered:
+		t.Fatal("second transaction started while the first held the lock")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if seen := <-entered; seen != "first" {
+		t.Errorf("second transaction saw title %q, want the first's write", seen)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"title":"second"`) {
+		t.Errorf("file = %s", data)
+	}
+}
+
+// newTestTx opens a transaction over the given JSONL lines
+func newTestTx(t *testing.T, lines ...string) *Tx {
+	t.Helper()
+	tx, err := newTx([]byte(strings.Join(lines, "")), "bd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// checkRoundTrip fails unless the bead put returned is what parsing the
+// transaction's output gives back
+func checkRoundTrip(t *testing.T, tx *Tx, bead *Bead) {
+	t.Helper()
+	reread, err := newTx(tx.bytes(), "bd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, ok := reread.beads[bead.ID]
+	if !ok {
+		t.Fatalf("%s not found after rewriting", bead.ID)
+	}
+	if changed := ChangedFields(bead, stored); len(changed) != 0 {
+		t.Errorf("fields differ after rewriting: %v", changed)
+	}
+}
+
+// errorKind names the kind of a write error for table tests: the field of a
+// validation error, "not found", or "" for nil
+func errorKind(err error) string {
+	var validation *ValidationError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &validation):
+		return validation.Field
+	case errors.Is(err, ErrBeadNotFound):
+		return "not found"
+	}
+	return err.Error()
+}
+
+func TestTxCreate(t *testing.T) {
+	one, five := 1, 5
+	tests := []struct {
+		name  string
+		input NewBead
+		check func(t *testing.T, bead *Bead)
+		err   string
+	}{
+		{"defaults", NewBead{Title: "new"}, func(t *testing.T, bead *Bead) {
+			if !strings.HasPrefix(bead.ID, "bd-") || bead.Status != StatusOpen || bead.Type != TypeTask || bead.Priority != 2 {
+				t.Errorf("got %s %s %s P%d", bead.ID, bead.Status, bead.Type, bead.Priority)
+			}
+			if bead.CreatedBy != DefaultActor {
+				t.Errorf("created_by = %q", bead.CreatedBy)
+			}
+		}, ""},
+		{"explicit fields", NewBead{ID: "bd-9", Title: "new", Priority: &one, Type: TypeBug, Labels: []string{"a"}}, func(t *testing.T, bead *Bead) {
+			if bead.ID != "bd-9" || bead.Priority != 1 || bead.Type != TypeBug || len(bead.Labels) != 1 {
+				t.Errorf("got %+v", bead)
+			}
+		}, ""},
+		{"child", NewBead{Parent: "bd-1", Title: "new"}, func(t *testing.T, bead *Bead) {
+			if bead.ID != "bd-1.3" {
+				t.Errorf("id = %s, want bd-1.3", bead.ID)
+			}
+		}, ""},
+		{"closed", NewBead{Title: "new", Status: StatusClosed}, func(t *testing.T, bead *Bead) {
+			if bead.ClosedAt == nil {
+				t.Error("closed_at not set")
+			}
+		}, ""},
+		{"id and parent", NewBead{ID: "bd-9", Parent: "bd-1", Title: "new"}, nil, "id"},
+		{"existing id", NewBead{ID: "bd-1", Title: "new"}, nil, "id"},
+		{"id with spaces", NewBead{ID: "bd 9", Title: "new"}, nil, "id"},
+		{"missing parent", NewBead{Parent: "bd-8", Title: "new"}, nil, "not found"},
+		{"no title", NewBead{Title: " "}, nil, "title"},
+		{"tombstone", NewBead{Title: "new", Status: StatusTombstone}, nil, "status"},
+		{"unknown type", NewBead{Title: "new", Type: "story"}, nil, "issue_type"},
+		{"priority out of range", NewBead{Title: "new", Priority: &five}, nil, "priority"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := newTestTx(t, record("bd-1", "one", 1), record("bd-1.2", "child", 1))
+			bead, err := tx.Create(&tt.input)
+			if kind := errorKind(err); kind != tt.err {
+				t.Fatalf("error = %v, want %q", err, tt.err)
+			}
+			if tt.err != "" {
+				if tx.changed {
+					t.Error("failed create changed the file")
+				}
+				return
+			}
+			tt.check(t, bead)
+			if tx.index[bead.ID] != len(tx.lines)-1 {
+				t.Error("new bead not appended")
+			}
+			checkRoundTrip(t, tx, bead)
+		})
+	}
+}
+
+func TestTxUpdate(t *testing.T) {
+	str := func(s string) *string { return &s }
+	status := func(s Status) *Status { return &s }
+	labels := func(l ...string) *[]string { return &l }
+	closed := `{"id":"bd-1","title":"one","status":"closed","close_reason":"done","closed_at":"2026-01-01T00:00:00Z","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}` + "\n"
+	labelled := `{"id":"bd-1","title":"one","labels":["a","b"],"created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}` + "\n"
+
+	tests := []struct {
+		name    string
+		line    string
+		patch   BeadPatch
+		changed bool
+		check   func(t *testing.T, bead *Bead)
+		err     string
+	}{
+		{"title", record("bd-1", "one", 1), BeadPatch{Title: str("two")}, true, func(t *testing.T, bead *Bead) {
+			if bead.Title != "two" || !bead.UpdatedAt.After(bead.CreatedAt) {
+				t.Errorf("title %q updated %v", bead.Title, bead.UpdatedAt)
+			}
+		}, ""},
+		{"same value", record("bd-1", "one", 1), BeadPatch{Title: str("one")}, false, nil, ""},
+		{"close", record("bd-1", "one", 1), BeadPatch{Status: status(StatusClosed), CloseReason: str("done")}, true, func(t *testing.T, bead *Bead) {
+			if bead.ClosedAt == nil || bead.CloseReason != "done" {
+				t.Errorf("closed_at %v reason %q", bead.ClosedAt, bead.CloseReason)
+			}
+		}, ""},
+		{"reopen", closed, BeadPatch{Status: status(StatusOpen)}, true, func(t *testing.T, bead *Bead) {
+			if bead.ClosedAt != nil || bead.CloseReason != "" {
+				t.Errorf("closed_at %v reason %q", bead.ClosedAt, bead.CloseReason)
+			}
+		}, ""},
+		{"labels", labelled, BeadPatch{Labels: labels("c"), AddLabels: []string{"d", "c"}, RemoveLabels: []string{"a"}}, true, func(t *testing.T, bead *Bead) {
+			if strings.Join(bead.Labels, ",") != "c,d" {
+				t.Errorf("labels = %v", bead.Labels)
+			}
+		}, ""},
+		{"unknown bead", record("bd-2", "two", 1), BeadPatch{Title: str("two")}, false, nil, "not found"},
+		{"empty title", record("bd-1", "one", 1), BeadPatch{Title: str("")}, false, nil, "title"},
+		{"unknown status", record("bd-1", "one", 1), BeadPatch{Status: status("done")}, false, nil, "status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := newTestTx(t, tt.line, record("bd-3", "other", 1))
+			before := string(tx.bytes())
+			bead, err := tx.Update("bd-1", &tt.patch)
+			if kind := errorKind(err); kind != tt.err {
+				t.Fatalf("error = %v, want %q", err, tt.err)
+			}
+			if tx.changed != tt.changed {
+				t.Errorf("changed = %v, want %v", tx.changed, tt.changed)
+			}
+			if !tt.changed {
+				if after := string(tx.bytes()); after != before {
+					t.Errorf("file rewritten:\n%s", after)
+				}
+				return
+			}
+			if tt.check != nil {
+				tt.check(t, bead)
+			}
+			if len(tx.lines) != 2 || tx.index["bd-1"] != 0 {
+				t.Error("record not rewritten in place")
+			}
+			checkRoundTrip(t, tx, bead)
+		})
+	}
+}
+
+func TestTxUpdateKeepsUnknownFields(t *testing.T) {
+	tx := newTestTx(t, `{"id":"bd-1","title":"one","x_custom":{"kept":true},"created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}`+"\n")
+	title := "two"
+	if _, err := tx.Update("bd-1", &BeadPatch{Title: &title}); err != nil {
+		t.Fatal(err)
+	}
+	if line := string(tx.lines[0]); !strings.Contains(line, `"x_custom":{"kept":true}`) || !strings.Contains(line, `"title":"two"`) {
+		t.Errorf("record = %s", line)
+	}
+}
+
+func TestNewTxRefusesConflictMarkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		refused bool
+	}{
+		{"clean", record("bd-1", "one", 1), false},
+		{"marker in a title", record("bd-1", "<<<<<<< HEAD", 1), false},
+		{"conflict", "<<<<<<< HEAD\n" + record("bd-1", "ours", 2) + "=======\n" + record("bd-1", "theirs", 2) + ">>>>>>> main\n", true},
+		{"indented marker", record("bd-2", "two", 1) + "  <<<<<<< HEAD\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTx([]byte(tt.content), "bd")
+			if refused := errors.Is(err, ErrMergeConflict); refused != tt.refused {
+				t.Errorf("err = %v, want refused %v", err, tt.refused)
+			}
+		})
+	}
+
+	// Update must leave a conflicted file alone
+	path := writeJSONL(t, "<<<<<<< HEAD\n", record("bd-1", "ours", 2), "=======\n", record("bd-1", "theirs", 2), ">>>>>>> main\n")
+	before, _ := os.ReadFile(path)
+	err := NewJSONLSource(path).Update(func(tx *Tx) error {
+		t.Error("update ran on a conflicted file")
+		return nil
+	})
+	if !errors.Is(err, ErrMergeConflict) {
+		t.Errorf("err = %v, want %v", err, ErrMergeConflict)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Error("conflicted file was rewritten")
+	}
+}
@@ -3,8 +3,11 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 // Config holds the server configuration
@@ -18,6 +21,66 @@ type Config struct {
 	JSONLPath   string // Path to beads.jsonl (legacy)
 	DBPath      string // Path to beads.db (SQLite)
 	UseSQLite   bool   // True if using SQLite backend
+	MaxBeadSize int64  // Longest JSONL record accepted, in bytes (0 = no limit)
+
+	HeartbeatInterval time.Duration // How often idle event streams get a heartbeat (0 = default)
+	TokensPath        string        // Access tokens file; authentication is off when empty
+	NoAuth            bool          // Allow serving beyond localhost without tokens
+	Metrics           bool          // Serve Prometheus metrics at /metrics
+
+	TLSCert       string // Certificate file for HTTPS
+	TLSKey        string // Private key file for HTTPS
+	TLSSelfSigned bool   // Serve HTTPS with a generated certificate cached in .beads/.seebeads
+
+	AllowedHosts   []string // Host header names accepted besides localhost, IP addresses and this machine ("*" for any)
+	AllowedOrigins []string // Origins besides the server's own allowed to make changes and cross-origin requests
+
+	// Projects are served together when set. BeadsPath and the data paths
+	// above then describe the first one, which is also served at /api.
+	Projects []Project
+}
+
+// DefaultAllowedOrigins are the web dev server's origins, so the frontend can
+// be developed against a running seebeads
+var DefaultAllowedOrigins = []string{"http://localhost:5173", "http://127.0.0.1:5173"}
+
+// TokensFileName is the tokens file looked for in the .beads directory
+const TokensFileName = "seebeads-tokens"
+
+// UserTokensFile returns the tokens file used when the .beads directory has
+// none, in the user config directory
+func UserTokensFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "seebeads", "tokens"), nil
+}
+
+// FindTokensFile returns the tokens file to use when --tokens isn't given:
+// seebeads-tokens in the .beads directory, then the user's tokens file, or ""
+// if there is neither. A tokens file in the repository that git doesn't
+// ignore is refused, since it could be committed.
+func FindTokensFile(beadsDir string) (string, error) {
+	path := filepath.Join(beadsDir, TokensFileName)
+	if fileExists(path) {
+		if ignored, inRepo := gitIgnored(path); inRepo && !ignored {
+			return "", fmt.Errorf("tokens file %s isn't ignored by git and could be committed: add %s to .beads/.gitignore", path, TokensFileName)
+		}
+		return path, nil
+	}
+
+	path, err := UserTokensFile()
+	if err != nil || !fileExists(path) {
+		return "", nil
+	}
+	return path, nil
+}
+
+// fileExists reports whether path can be stat'ed
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
 }
 
 // DefaultConfig returns the default configuration
@@ -28,6 +91,9 @@ func DefaultConfig() *Config {
 		OpenBrowser: false,
 		AgentMode:   false,
 		NoWatch:     false,
+
+		HeartbeatInterval: 30 * time.Second,
+		AllowedOrigins:    DefaultAllowedOrigins,
 	}
 }
 
@@ -99,6 +165,52 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid port: %d", c.Port)
 	}
 
+	if c.MaxBeadSize < 0 {
+		return fmt.Errorf("invalid max bead size: %d", c.MaxBeadSize)
+	}
+
+	if c.HeartbeatInterval < 0 {
+		return fmt.Errorf("invalid heartbeat interval: %s", c.HeartbeatInterval)
+	}
+
+	if c.NoAuth && c.TokensPath != "" {
+		return fmt.Errorf("--no-auth can't be combined with --tokens")
+	}
+
+	if c.TokensPath != "" {
+		if _, err := os.Stat(c.TokensPath); err != nil {
+			return fmt.Errorf("tokens file not found: %s", c.TokensPath)
+		}
+	}
+
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be used together")
+	}
+
+	if c.TLSSelfSigned && c.TLSCert != "" {
+		return fmt.Errorf("--tls-self-signed can't be combined with --tls-cert")
+	}
+
+	for _, path := range []string{c.TLSCert, c.TLSKey} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("TLS file not found: %s", path)
+		}
+	}
+
+	for _, origin := range c.AllowedOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			return fmt.Errorf("invalid allowed origin %q: use scheme://host[:port]", origin)
+		}
+	}
+
+	if err := validateProjects(c.Projects); err != nil {
+		return err
+	}
+
 	if c.BeadsPath != "" {
 		if _, err := os.Stat(c.BeadsPath); err != nil {
 			return fmt.Errorf("beads path not found: %s", c.BeadsPath)
@@ -113,7 +225,15 @@ func (c *Config) Address() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
 }
 
+// UsesTLS reports whether the server is configured for HTTPS
+func (c *Config) UsesTLS() bool {
+	return c.TLSCert != "" || c.TLSSelfSigned
+}
+
 // URL returns the full server URL
 func (c *Config) URL() string {
+	if c.UsesTLS() {
+		return fmt.Sprintf("https://%s", c.Address())
+	}
 	return fmt.Sprintf("http://%s", c.Address())
 }
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindTokensFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		git       bool   // The project is a git repository
+		gitignore string // Contents of .beads/.gitignore
+		want      bool   // The .beads tokens file is used
+		err       string
+	}{
+		{"outside a repository", false, "", true, ""},
+		{"ignored", true, TokensFileName + "\n", true, ""},
+		{"ignored by pattern", true, "seebeads-*\n", true, ""},
+		{"not ignored", true, "*.db\n", false, "isn't ignored by git"},
+		{"ignore negated", true, TokensFileName + "\n!" + TokensFileName + "\n", false, "isn't ignored by git"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			t.Setenv("HOME", t.TempDir())
+			root := t.TempDir()
+			beadsDir := filepath.Join(root, ".beads")
+			if err := os.Mkdir(beadsDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if tt.git {
+				if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.WriteFile(filepath.Join(beadsDir, ".gitignore"), []byte(tt.gitignore), 0644); err != nil {
+				t.Fatal(err)
+			}
+			tokens := filepath.Join(beadsDir, TokensFileName)
+			if err := os.WriteFile(tokens, []byte("# none\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			path, err := FindTokensFile(beadsDir)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (path == tokens) != tt.want {
+				t.Errorf("path = %q, want %q", path, tokens)
+			}
+		})
+	}
+}
+
+func TestFindTokensFileFallsBackToUserConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	beadsDir := t.TempDir()
+
+	if path, err := FindTokensFile(beadsDir); err != nil || path != "" {
+		t.Fatalf("no tokens file: path = %q, err = %v", path, err)
+	}
+
+	user, err := UserTokensFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(user), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(user, []byte("# none\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if path, err := FindTokensFile(beadsDir); err != nil || path != user {
+		t.Errorf("path = %q, err = %v, want %q", path, err, user)
+	}
+}
+
+func TestGitIgnoredDirectory(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".git", "info"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "private", "keys"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".git", "info", "exclude"), []byte("private/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A deeper negation can't re-include a file in an ignored directory
+	if err := os.WriteFile(filepath.Join(root, "private", "keys", ".gitignore"), []byte("!*\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ignored, inRepo := gitIgnored(filepath.Join(root, "private", "keys", "tokens"))
+	if !ignored || !inRepo {
+		t.Errorf("ignored = %v, inRepo = %v, want both true", ignored, inRepo)
+	}
+	ignored, _ = gitIgnored(filepath.Join(root, "public", "tokens"))
+	if ignored {
+		t.Error("file outside the excluded directory is ignored")
+	}
+}
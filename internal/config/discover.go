@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultDiscoverDepth is how many directories below the root Discover looks
+const DefaultDiscoverDepth = 4
+
+// DiscoveredProject is a .beads directory found under a workspace root
+type DiscoveredProject struct {
+	Project
+	Rel       string // Project directory relative to the root, using '/' ("." for the root)
+	BeadsDir  string
+	DataPath  string // Chosen as by FindBeadsDataPath; empty if DataErr is set
+	UseSQLite bool
+	DataErr   error // Why the directory has no usable data file
+}
+
+// Discover walks down from root looking for .beads directories, skipping
+// whatever .gitignore files ignore and anything more than maxDepth
+// directories down. Projects are named after their directory, or its path
+// relative to root when two directories share a name.
+func Discover(root string, maxDepth int) ([]DiscoveredProject, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+
+	var found []DiscoveredProject
+	var walk func(dir, rel string, depth int, ignore *gitignore) error
+	walk = func(dir, rel string, depth int, ignore *gitignore) error {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			// Unreadable directories are skipped, as git would
+			if rel != "" && os.IsPermission(err) {
+				return nil
+			}
+			return err
+		}
+		ignore = ignore.load(dir, rel)
+
+		for _, entry := range entries {
+			if !isDir(dir, entry) {
+				continue
+			}
+			name := entry.Name()
+			childRel := name
+			if rel != "" {
+				childRel = rel + "/" + name
+			}
+			if name == ".git" || ignore.ignored(childRel, true) {
+				continue
+			}
+
+			if name == ".beads" {
+				found = append(found, discovered(filepath.Join(dir, name), rel))
+				continue
+			}
+			if depth < maxDepth {
+				if err := walk(filepath.Join(dir, name), childRel, depth+1, ignore); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(root, "", 0, &gitignore{}); err != nil {
+		return nil, err
+	}
+
+	nameProjects(found)
+	return found, nil
+}
+
+// isDir reports whether an entry is a directory, following symlinks
+func isDir(dir string, entry fs.DirEntry) bool {
+	if entry.IsDir() {
+		return true
+	}
+	if entry.Type()&fs.ModeSymlink == 0 {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, entry.Name()))
+	return err == nil && info.IsDir()
+}
+
+// discovered describes the .beads directory of the project at rel
+func discovered(beadsDir, rel string) DiscoveredProject {
+	project := DiscoveredProject{
+		Project:  Project{Path: filepath.Dir(beadsDir)},
+		Rel:      rel,
+		BeadsDir: beadsDir,
+	}
+	if rel == "" {
+		project.Rel = "."
+	}
+	project.DataPath, project.UseSQLite, project.DataErr = FindBeadsDataPath(beadsDir)
+	return project
+}
+
+// nameProjects names each project after its directory, falling back to its
+// path relative to the root (with '/' as '-') when names collide. Any name
+// still taken, by the root project or after sanitizing, gets a numeric suffix
+// in path order, so the root keeps its plain name.
+func nameProjects(projects []DiscoveredProject) {
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Rel < projects[j].Rel })
+
+	counts := make(map[string]int)
+	for i := range projects {
+		counts[ProjectName(projects[i].Path)]++
+	}
+	used := make(map[string]bool)
+	for i := range projects {
+		project := &projects[i]
+		name := ProjectName(project.Path)
+		if counts[name] > 1 && project.Rel != "." {
+			name = strings.ReplaceAll(project.Rel, "/", "-")
+		}
+		if !projectNamePattern.MatchString(name) {
+			name = sanitizeProjectName(name)
+		}
+		project.Name = name
+		for n := 2; used[project.Name]; n++ {
+			project.Name = fmt.Sprintf("%s-%d", name, n)
+		}
+		used[project.Name] = true
+	}
+}
+
+// sanitizeProjectName replaces characters that aren't allowed in a project name
+func sanitizeProjectName(name string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return '-'
+	}, name)
+	cleaned = strings.TrimLeft(cleaned, "-_.")
+	if cleaned == "" {
+		return "project"
+	}
+	return cleaned
+}
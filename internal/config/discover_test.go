@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeProjects creates a .beads directory with an issues.jsonl under root
+// for each relative path ("." for root itself)
+func makeProjects(t *testing.T, root string, rels ...string) {
+	t.Helper()
+	for _, rel := range rels {
+		dir := filepath.Join(root, filepath.FromSlash(rel), ".beads")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "issues.jsonl"), []byte("{}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDiscoverNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		rels  []string
+		names []string // In Rel order
+	}{
+		{"distinct", []string{".", "api", "web"}, []string{"foo", "api", "web"}},
+		{"nested projects share a name", []string{"a/web", "b/web"}, []string{"a-web", "b-web"}},
+		{"nested project shares the root's name deeper down", []string{".", "sub/foo"}, []string{"foo", "sub-foo"}},
+		{"nested project shares the root's name and path", []string{".", "foo"}, []string{"foo", "foo-2"}},
+		{"relative path matches another name", []string{"a-web", "a/web", "b/web"}, []string{"a-web", "a-web-2", "b-web"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := filepath.Join(t.TempDir(), "foo")
+			makeProjects(t, root, tt.rels...)
+
+			found, err := Discover(root, DefaultDiscoverDepth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rels, names []string
+			projects := make([]Project, len(found))
+			for i, project := range found {
+				rels = append(rels, project.Rel)
+				names = append(names, project.Name)
+				projects[i] = project.Project
+			}
+			if strings.Join(rels, ",") != strings.Join(tt.rels, ",") {
+				t.Fatalf("found %v, want %v", rels, tt.rels)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.names, ",") {
+				t.Errorf("names = %v, want %v", names, tt.names)
+			}
+			if err := validateProjects(projects); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestDiscoverSkipsIgnoredAndDeepDirectories(t *testing.T) {
+	root := t.TempDir()
+	makeProjects(t, root, "app", "vendor/lib", "a/b/c/d", "a/b/c/d/e")
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("vendor/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "empty", ".beads"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := Discover(root, DefaultDiscoverDepth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rels []string
+	for _, project := range found {
+		rels = append(rels, project.Rel)
+		if project.Rel == "empty" && project.DataErr == nil {
+			t.Error("empty .beads has no DataErr")
+		}
+	}
+	if got := strings.Join(rels, ","); got != "a/b/c/d,app,empty" {
+		t.Errorf("found %s", got)
+	}
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule is one pattern from a .gitignore file
+type ignoreRule struct {
+	base     string   // Directory of the .gitignore, relative to the walk root, using '/'
+	segments []string // Pattern split on '/'
+	anchored bool     // Matches from base rather than at any depth
+	dirOnly  bool     // Pattern ended in '/'
+	negate   bool     // Pattern started with '!'
+}
+
+// gitignore holds the rules in effect for a directory. Rules from deeper
+// files come later, and the last matching rule wins, as in git.
+type gitignore struct {
+	rules []ignoreRule
+}
+
+// load returns the rules for dir: the parent's plus those in dir/.gitignore.
+// rel is dir relative to the walk root.
+func (g *gitignore) load(dir, rel string) *gitignore {
+	return g.loadFile(filepath.Join(dir, ".gitignore"), rel)
+}
+
+// loadFile returns the parent's rules plus those in an ignore file whose
+// patterns are relative to rel
+func (g *gitignore) loadFile(name, rel string) *gitignore {
+	file, err := os.Open(name)
+	if err != nil {
+		return g
+	}
+	defer file.Close()
+
+	child := &gitignore{rules: append([]ignoreRule(nil), g.rules...)}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(scanner.Text(), rel); ok {
+			child.rules = append(child.rules, rule)
+		}
+	}
+	return child
+}
+
+// gitIgnored reports whether git would ignore file, going by the repository's
+// info/exclude and the .gitignore files from its root down. inRepo is false if
+// file isn't inside a git repository.
+func gitIgnored(file string) (ignored, inRepo bool) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return false, false
+	}
+	root := filepath.Dir(abs)
+	for !fileExists(filepath.Join(root, ".git")) {
+		parent := filepath.Dir(root)
+		if parent == root {
+			return false, false
+		}
+		root = parent
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return false, false
+	}
+	rel = filepath.ToSlash(rel)
+
+	g := (&gitignore{}).loadFile(filepath.Join(root, ".git", "info", "exclude"), "")
+	g = g.load(root, "")
+
+	// Files in an ignored directory are ignored whatever deeper rules say
+	parts := strings.Split(rel, "/")
+	dir := root
+	for i, part := range parts[:len(parts)-1] {
+		relDir := strings.Join(parts[:i+1], "/")
+		if g.ignored(relDir, true) {
+			return true, true
+		}
+		dir = filepath.Join(dir, part)
+		g = g.load(dir, relDir)
+	}
+	return g.ignored(rel, false), true
+}
+
+// parseIgnoreRule parses one .gitignore line
+func parseIgnoreRule(line, base string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:] // Escaped leading '#' or '!'
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	// A slash anywhere but the end ties the pattern to the file's directory
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	rule.segments = strings.Split(line, "/")
+	return rule, true
+}
+
+// ignored reports whether the path rel (relative to the walk root) is ignored
+func (g *gitignore) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range g.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.matches(rel) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// matches reports whether the rule applies to rel
+func (r ignoreRule) matches(rel string) bool {
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = strings.TrimPrefix(rel, r.base+"/")
+	}
+	if !r.anchored {
+		return matchSegments(r.segments, []string{path.Base(rel)})
+	}
+	return matchSegments(r.segments, strings.Split(rel, "/"))
+}
+
+// matchSegments matches a path against a pattern segment by segment, where
+// "**" matches any number of segments
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
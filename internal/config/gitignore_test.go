@@ -0,0 +1,121 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseIgnoreRule(t *testing.T) {
+	tests := []struct {
+		line string
+		ok   bool
+		rule ignoreRule
+	}{
+		{"", false, ignoreRule{}},
+		{"# comment", false, ignoreRule{}},
+		{"   ", false, ignoreRule{}},
+		{"/", false, ignoreRule{}},
+		{"node_modules", true, ignoreRule{segments: []string{"node_modules"}}},
+		{"node_modules/", true, ignoreRule{segments: []string{"node_modules"}, dirOnly: true}},
+		{"build/ \t", true, ignoreRule{segments: []string{"build"}, dirOnly: true}},
+		{"/vendor", true, ignoreRule{segments: []string{"vendor"}, anchored: true}},
+		{"docs/*.md", true, ignoreRule{segments: []string{"docs", "*.md"}, anchored: true}},
+		{"**/tmp/", true, ignoreRule{segments: []string{"**", "tmp"}, anchored: true, dirOnly: true}},
+		{"!keep", true, ignoreRule{segments: []string{"keep"}, negate: true}},
+		{`\#file`, true, ignoreRule{segments: []string{"#file"}}},
+		{`\!file`, true, ignoreRule{segments: []string{"!file"}}},
+	}
+	for _, tt := range tests {
+		rule, ok := parseIgnoreRule(tt.line, "sub")
+		if ok != tt.ok {
+			t.Errorf("parseIgnoreRule(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		tt.rule.base = "sub"
+		if !reflect.DeepEqual(rule, tt.rule) {
+			t.Errorf("parseIgnoreRule(%q) = %+v, want %+v", tt.line, rule, tt.rule)
+		}
+	}
+}
+
+func TestMatchSegments(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"a", "a", true},
+		{"a", "b", false},
+		{"a/b", "a/b", true},
+		{"a/b", "a/b/c", false},
+		{"a/*", "a/b", true},
+		{"a/*", "a/b/c", false},
+		{"*.go", "main.go", true},
+		{"a?c", "abc", true},
+		{"[ab]x", "bx", true},
+		{"[", "[", false}, // Malformed patterns never match
+		{"**/b", "b", true},
+		{"**/b", "a/x/b", true},
+		{"a/**", "a/x/y", true},
+		{"a/**", "a", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "a/x/y/c", false},
+	}
+	for _, tt := range tests {
+		got := matchSegments(strings.Split(tt.pattern, "/"), strings.Split(tt.name, "/"))
+		if got != tt.match {
+			t.Errorf("matchSegments(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.match)
+		}
+	}
+}
+
+func TestGitignoreIgnored(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(root, rel, ".gitignore")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("", "# top\nbuild/\n*.log\n/vendor\n!keep.log\n")
+	write("sub", "local/\n!debug.log\n")
+
+	top := (&gitignore{}).load(root, "")
+	sub := top.load(filepath.Join(root, "sub"), "sub")
+
+	tests := []struct {
+		ignore *gitignore
+		rel    string
+		isDir  bool
+		want   bool
+	}{
+		{top, "build", true, true},
+		{top, "build", false, false}, // Directory-only rule
+		{top, "x/build", true, true},
+		{top, "debug.log", false, true},
+		{top, "keep.log", false, false}, // Negated later
+		{top, "vendor", true, true},
+		{top, "x/vendor", true, false}, // Anchored to the root
+		{sub, "sub/local", true, true},
+		{sub, "local", true, false},          // Rules only apply below their file
+		{sub, "sub/other.log", false, true},  // Inherited
+		{sub, "sub/debug.log", false, false}, // Deeper rules win
+		{sub, "debug.log", false, true},
+	}
+	for _, tt := range tests {
+		if got := tt.ignore.ignored(tt.rel, tt.isDir); got != tt.want {
+			t.Errorf("ignored(%q, dir=%v) = %v, want %v", tt.rel, tt.isDir, got, tt.want)
+		}
+	}
+}
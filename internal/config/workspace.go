@@ -0,0 +1,112 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Project names a .beads directory served in multi-project mode
+type Project struct {
+	Name string `json:"name"`
+	Path string `json:"path"` // The project directory or its .beads directory
+}
+
+// Workspace is the file read by --workspace, listing projects to serve together:
+//
+//	{"projects": [{"name": "api", "path": "services/api"}]}
+//
+// Relative paths are resolved against the workspace file's directory.
+type Workspace struct {
+	Projects []Project `json:"projects"`
+}
+
+// projectNamePattern keeps project names usable as a URL path segment
+var projectNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ParseProject parses a --project value, "name=path" or just "path", in
+// which case the name is the project directory's name
+func ParseProject(value string) (Project, error) {
+	name, path, ok := strings.Cut(value, "=")
+	if !ok {
+		path, name = value, ""
+	}
+	if path == "" {
+		return Project{}, fmt.Errorf("invalid project %q: use name=path", value)
+	}
+	project := Project{Name: name, Path: path}
+	if project.Name == "" {
+		project.Name = ProjectName(path)
+	}
+	return project, nil
+}
+
+// LoadWorkspace reads the projects from a workspace file
+func LoadWorkspace(path string) ([]Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace: %w", err)
+	}
+
+	var workspace Workspace
+	if err := json.Unmarshal(data, &workspace); err != nil {
+		return nil, fmt.Errorf("invalid workspace %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	for i := range workspace.Projects {
+		project := &workspace.Projects[i]
+		if project.Path == "" {
+			return nil, fmt.Errorf("invalid workspace %s: project %q has no path", path, project.Name)
+		}
+		if !filepath.IsAbs(project.Path) {
+			project.Path = filepath.Join(dir, project.Path)
+		}
+		if project.Name == "" {
+			project.Name = ProjectName(project.Path)
+		}
+	}
+	return workspace.Projects, nil
+}
+
+// ProjectName returns the default name of a project: its directory's name
+func ProjectName(path string) string {
+	path = filepath.Clean(path)
+	if filepath.Base(path) == ".beads" {
+		path = filepath.Dir(path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return filepath.Base(path)
+}
+
+// BeadsDir returns the project's .beads directory
+func (p Project) BeadsDir() (string, error) {
+	path := filepath.Clean(p.Path)
+	if filepath.Base(path) != ".beads" {
+		path = filepath.Join(path, ".beads")
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("project %s: no .beads directory in %s", p.Name, p.Path)
+	}
+	return path, nil
+}
+
+// validateProjects checks that project names are usable in URLs and unique
+func validateProjects(projects []Project) error {
+	seen := make(map[string]bool)
+	for _, project := range projects {
+		if !projectNamePattern.MatchString(project.Name) {
+			return fmt.Errorf("invalid project name %q: use letters, digits, '.', '_' and '-'", project.Name)
+		}
+		if seen[project.Name] {
+			return fmt.Errorf("duplicate project name %q", project.Name)
+		}
+		seen[project.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Role is what a token is allowed to do
+type Role string
+
+const (
+	RoleReadOnly  Role = "read-only"  // GET endpoints, SSE and WebSocket events
+	RoleReadWrite Role = "read-write" // Everything, including writes and agent mode
+)
+
+// authCookie holds the token for browsers, since EventSource and WebSocket
+// can't send an Authorization header
+const authCookie = "seebeads_token"
+
+// accessToken is one entry in the tokens file
+type accessToken struct {
+	secret string
+	role   Role
+	name   string
+}
+
+// tokenSet is the parsed tokens file. A nil set disables authentication.
+type tokenSet struct {
+	tokens []accessToken
+}
+
+// loadTokens reads a tokens file. Each non-blank, non-comment line is
+//
+//	<token> <read-only|read-write> [name]
+//
+// Outside Windows, whose permissions don't map to mode bits, files other
+// users can access are refused.
+func loadTokens(path string) (*tokenSet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if mode := info.Mode().Perm(); mode&0077 != 0 && runtime.GOOS != "windows" {
+		return nil, fmt.Errorf("%s is accessible by other users (mode %04o): run 'chmod 600 %s'", path, mode, path)
+	}
+
+	set := &tokenSet{}
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: expected '<token> <role> [name]'", path, lineNum)
+		}
+		role := Role(fields[1])
+		if role != RoleReadOnly && role != RoleReadWrite {
+			return nil, fmt.Errorf("%s:%d: unknown role %q (use %s or %s)", path, lineNum, role, RoleReadOnly, RoleReadWrite)
+		}
+		if len(fields[0]) < 16 {
+			return nil, fmt.Errorf("%s:%d: token is too short, use at least 16 characters", path, lineNum)
+		}
+		set.tokens = append(set.tokens, accessToken{
+			secret: fields[0],
+			role:   role,
+			name:   strings.Join(fields[2:], " "),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(set.tokens) == 0 {
+		return nil, fmt.Errorf("%s has no tokens", path)
+	}
+	return set, nil
+}
+
+// lookup finds the token matching secret, comparing in constant time
+func (t *tokenSet) lookup(secret string) *accessToken {
+	if secret == "" {
+		return nil
+	}
+	var found *accessToken
+	for i := range t.tokens {
+		if subtle.ConstantTimeCompare([]byte(t.tokens[i].secret), []byte(secret)) == 1 {
+			found = &t.tokens[i]
+		}
+	}
+	return found
+}
+
+// allows reports whether the role may make a request with this method
+func (r Role) allows(method string) bool {
+	if r == RoleReadWrite {
+		return true
+	}
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+type roleKey struct{}
+
+// requestRole returns the role of an authenticated request. Without
+// authentication configured every request has full access.
+func requestRole(r *http.Request) Role {
+	if role, ok := r.Context().Value(roleKey{}).(Role); ok {
+		return role
+	}
+	return RoleReadWrite
+}
+
+// requestCredential returns the token a request was made with, from the
+// Authorization header, the auth cookie or a ?token= link
+func requestCredential(r *http.Request) (secret string, fromQuery bool) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if scheme, value, ok := strings.Cut(header, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(value), false
+		}
+	}
+	if cookie, err := r.Cookie(authCookie); err == nil {
+		return cookie.Value, false
+	}
+	return r.URL.Query().Get("token"), true
+}
+
+// setAuthCookie remembers a token in the browser. SameSite=Lax, so a login
+// link opened from another site (chat, email) still sends it on the redirect
+// that follows; the origin checks in protect keep other sites from writing.
+func setAuthCookie(w http.ResponseWriter, r *http.Request, secret string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     authCookie,
+		Value:    secret,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// requireAuth rejects requests without a valid token and read-only tokens
+// making changes. Opening any page with ?token=<token> logs the browser in.
+func (s *Server) requireAuth(next http.Handler) http.Handler {
+	if s.tokens == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/login" || r.URL.Path == "/api/logout" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		secret, fromQuery := requestCredential(r)
+		token := s.tokens.lookup(secret)
+		if token == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="seebeads"`)
+			errorResponse(w, http.StatusUnauthorized, "Authentication required: log in with ?token=<token> or an Authorization: Bearer header")
+			return
+		}
+
+		// Swap a login link for the cookie so the token leaves the address bar
+		if fromQuery && r.Method == http.MethodGet && !strings.HasPrefix(r.URL.Path, "/api/") {
+			setAuthCookie(w, r, secret, 0)
+			clean := *r.URL
+			query := clean.Query()
+			query.Del("token")
+			clean.RawQuery = query.Encode()
+			http.Redirect(w, r, clean.RequestURI(), http.StatusFound)
+			return
+		}
+
+		if !token.role.allows(r.Method) {
+			errorResponse(w, http.StatusForbidden, "This token is read-only")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), roleKey{}, token.role)))
+	})
+}
+
+// POST /api/login - exchanges a token for the auth cookie
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		errorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if s.tokens == nil {
+		jsonResponse(w, http.StatusOK, map[string]interface{}{"role": RoleReadWrite})
+		return
+	}
+	token := s.tokens.lookup(body.Token)
+	if token == nil {
+		errorResponse(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	setAuthCookie(w, r, token.secret, 0)
+	jsonResponse(w, http.StatusOK, map[string]interface{}{
+		"role": token.role,
+		"name": token.name,
+	})
+}
+
+// POST /api/logout - clears the auth cookie
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	setAuthCookie(w, r, "", -1)
+	jsonResponse(w, http.StatusOK, map[string]bool{"loggedOut": true})
+}
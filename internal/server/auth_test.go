@@ -0,0 +1,129 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const (
+	readToken  = "r0000000000000000000000000000000"
+	writeToken = "w0000000000000000000000000000000"
+)
+
+// writeTokens writes a tokens file with the given mode
+func writeTokens(t *testing.T, content string, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tokens")
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, mode); err != nil { // Not subject to the umask
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		mode    os.FileMode
+		err     string
+	}{
+		{"valid", "# comment\n\n" + readToken + " read-only wall\n" + writeToken + " read-write alice\n", 0600, ""},
+		{"group readable", writeToken + " read-write\n", 0640, "accessible by other users"},
+		{"world readable", writeToken + " read-write\n", 0604, "accessible by other users"},
+		{"unknown role", writeToken + " admin\n", 0600, "unknown role"},
+		{"missing role", writeToken + "\n", 0600, "expected"},
+		{"short token", "abc read-only\n", 0600, "too short"},
+		{"no tokens", "# nothing\n", 0600, "has no tokens"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if runtime.GOOS == "windows" && tt.mode != 0600 {
+				t.Skip("permissions aren't checked on Windows")
+			}
+			set, err := loadTokens(writeTokens(t, tt.content, tt.mode))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(set.tokens) != 2 || set.tokens[0].role != RoleReadOnly || set.tokens[1].name != "alice" {
+				t.Errorf("tokens = %+v", set.tokens)
+			}
+		})
+	}
+}
+
+func TestRequireAuth(t *testing.T) {
+	s, _ := newTestServer(t)
+	tokens, err := loadTokens(writeTokens(t, readToken+" read-only\n"+writeToken+" read-write\n", 0600))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.tokens = tokens
+	handler := s.handler()
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		bearer   string
+		cookie   string
+		body     string
+		status   int
+		location string
+	}{
+		{"no token", "GET", "/api/beads", "", "", "", http.StatusUnauthorized, ""},
+		{"wrong token", "GET", "/api/beads", "x" + readToken[1:], "", "", http.StatusUnauthorized, ""},
+		{"read with read-only token", "GET", "/api/beads", readToken, "", "", http.StatusOK, ""},
+		{"read with cookie", "GET", "/api/beads", "", readToken, "", http.StatusOK, ""},
+		{"read with query on the API", "GET", "/api/beads?token=" + readToken, "", "", "", http.StatusOK, ""},
+		{"write with read-only token", "POST", "/api/beads", readToken, "", `{"title":"two"}`, http.StatusForbidden, ""},
+		{"agent mode with read-only token", "POST", "/api/agent-mode", "", readToken, `{"enabled":true}`, http.StatusForbidden, ""},
+		{"write with read-write token", "POST", "/api/beads", writeToken, "", `{"title":"two"}`, http.StatusCreated, ""},
+		{"login link", "GET", "/?view=list&token=" + readToken, "", "", "", http.StatusFound, "/?view=list"},
+		{"login without a token", "POST", "/api/login", "", "", `{"token":"` + readToken + `"}`, http.StatusOK, ""},
+		{"logout without a token", "POST", "/api/logout", "", "", "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Host = "127.0.0.1:3456"
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: authCookie, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+			if location := rec.Header().Get("Location"); location != tt.location {
+				t.Errorf("Location = %q, want %q", location, tt.location)
+			}
+			// Lax, since Strict cookies are left off the redirect when the
+			// link was opened from another site
+			cookie := rec.Header().Get("Set-Cookie")
+			if tt.location != "" && (!strings.Contains(cookie, authCookie+"="+readToken) || !strings.Contains(cookie, "SameSite=Lax")) {
+				t.Errorf("login link set cookie %q, want the token with SameSite=Lax", cookie)
+			}
+		})
+	}
+}
@@ -2,10 +2,14 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
-	"path/filepath"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/taylorkpotter/seeBeads/internal/beads"
@@ -23,16 +27,132 @@ func errorResponse(w http.ResponseWriter, status int, message string) {
 	jsonResponse(w, status, map[string]string{"error": message})
 }
 
+// notModified sets validators for a response built from snap and reports
+// whether the client's cached copy is still current, in which case a 304 has
+// already been written. extra distinguishes responses that can change without
+// a new generation. ETags are weak because map ordering makes the JSON
+// byte-for-byte unstable even when the data is the same.
+func (s *Server) notModified(w http.ResponseWriter, r *http.Request, snap *beads.Snapshot, extra ...string) bool {
+	tag := fmt.Sprintf("%s-%d", s.instanceID, snap.Generation)
+	for _, e := range extra {
+		tag += "-" + e
+	}
+	etag := `W/"` + tag + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if !snap.LastUpdated.IsZero() {
+		w.Header().Set("Last-Modified", snap.LastUpdated.UTC().Format(http.TimeFormat))
+	}
+
+	// If-None-Match takes precedence over If-Modified-Since (RFC 9110)
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		if !etagMatches(match, etag) {
+			return false
+		}
+	} else if since := r.Header.Get("If-Modified-Since"); since != "" && len(extra) == 0 {
+		t, err := http.ParseTime(since)
+		if err != nil || snap.LastUpdated.IsZero() || snap.LastUpdated.Truncate(time.Second).After(t) {
+			return false
+		}
+	} else {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches implements weak comparison against an If-None-Match header
+func etagMatches(header, etag string) bool {
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
+
+// statsTagInterval is how long a stats ETag stays valid without a new
+// generation. Stale, ready and velocity counts depend on the current time, so
+// the tag moves on with it and a dashboard left open on a quiet project still
+// sees them change.
+const statsTagInterval = time.Minute
+
 // GET /api/stats
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
-	stats := s.graph.GetStats()
+	snap := s.project(r).Graph.Snapshot()
+	bucket := time.Now().Truncate(statsTagInterval).Unix()
+	if s.notModified(w, r, snap, strconv.FormatInt(bucket, 10)) {
+		return
+	}
+
+	stats := snap.GetStats()
 	jsonResponse(w, http.StatusOK, stats)
 }
 
-// GET /api/beads
-func (s *Server) handleBeads(w http.ResponseWriter, r *http.Request) {
+// defaultSeriesPoints is how many intervals a series covers without ?from
+const defaultSeriesPoints = 30
+
+// GET /api/stats/series?from=2026-01-01&to=2026-01-31&interval=day - open
+// and closed counts over time for the beads matching the usual filters
+func (s *Server) handleStatsSeries(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
+	interval, err := beads.ParseInterval(query.Get("interval"))
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	to := time.Now()
+	if toStr := query.Get("to"); toStr != "" {
+		if to, err = parseSeriesTime(toStr); err != nil {
+			errorResponse(w, http.StatusBadRequest, "Invalid to: "+err.Error())
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -(defaultSeriesPoints-1)*interval.Days())
+	if fromStr := query.Get("from"); fromStr != "" {
+		if from, err = parseSeriesTime(fromStr); err != nil {
+			errorResponse(w, http.StatusBadRequest, "Invalid from: "+err.Error())
+			return
+		}
+	}
+	if from.After(to) {
+		errorResponse(w, http.StatusBadRequest, "from must not be after to")
+		return
+	}
+
+	snap := s.project(r).Graph.Snapshot()
+	// The default range moves with the date even when the data doesn't
+	if s.notModified(w, r, snap, to.UTC().Format("2006-01-02")) {
+		return
+	}
+	points, err := snap.GetSeries(parseFilter(query), from, to, interval)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, map[string]interface{}{
+		"interval": interval,
+		"points":   points,
+	})
+}
+
+// parseSeriesTime reads a date (2026-01-31) or an RFC 3339 timestamp
+func parseSeriesTime(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
 
+// parseFilter reads the bead filter shared by /api/beads and /api/events
+// from query parameters. Pagination is left to the caller.
+func parseFilter(query url.Values) *beads.Filter {
 	filter := &beads.Filter{}
 
 	// Parse status filter
@@ -68,6 +188,19 @@ func (s *Server) handleBeads(w http.ResponseWriter, r *http.Request) {
 		filter.Labels = strings.Split(labelsStr, ",")
 	}
 
+	// Parse assignee filter
+	if assigneeStr := query.Get("assignee"); assigneeStr != "" {
+		filter.Assignee = strings.Split(assigneeStr, ",")
+	}
+
+	// Parse epic filter
+	filter.Epic = query.Get("epic")
+
+	// Parse ID filter
+	if idsStr := query.Get("ids"); idsStr != "" {
+		filter.IDs = strings.Split(idsStr, ",")
+	}
+
 	// Parse search filter
 	filter.Search = query.Get("search")
 
@@ -76,6 +209,14 @@ func (s *Server) handleBeads(w http.ResponseWriter, r *http.Request) {
 		filter.Ready = true
 	}
 
+	return filter
+}
+
+// GET /api/beads
+func (s *Server) handleBeads(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := parseFilter(query)
+
 	// Parse pagination
 	if limitStr := query.Get("limit"); limitStr != "" {
 		if limit, err := strconv.Atoi(limitStr); err == nil {
@@ -93,13 +234,18 @@ func (s *Server) handleBeads(w http.ResponseWriter, r *http.Request) {
 		filter.Limit = 100
 	}
 
-	results := s.graph.GetBeads(filter)
+	// Both queries must see the same data for hasMore to be right
+	snap := s.project(r).Graph.Snapshot()
+	if s.notModified(w, r, snap) {
+		return
+	}
+	results := snap.GetBeads(filter)
 
 	// Get total count without pagination for hasMore
 	allFilter := *filter
 	allFilter.Limit = 0
 	allFilter.Offset = 0
-	total := len(s.graph.GetBeads(&allFilter))
+	total := len(snap.GetBeads(&allFilter))
 
 	response := map[string]interface{}{
 		"beads":   results,
@@ -115,11 +261,17 @@ func (s *Server) handleBead(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	bead := s.graph.GetBead(id)
+	// Related beads are read through the pinned snapshot, so they can't
+	// change underneath us during a reload
+	snap := s.project(r).Graph.Snapshot()
+	bead := snap.GetBead(id)
 	if bead == nil {
 		errorResponse(w, http.StatusNotFound, "Bead not found")
 		return
 	}
+	if s.notModified(w, r, snap) {
+		return
+	}
 
 	// Build response with related beads
 	response := map[string]interface{}{
@@ -157,31 +309,101 @@ func (s *Server) handleBead(w http.ResponseWriter, r *http.Request) {
 
 // GET /api/health
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	// Return basename only to avoid leaking absolute filesystem paths
-	beadsFile := filepath.Base(s.config.JSONLPath)
+	graph := s.project(r).Graph
+	snap := graph.Snapshot()
+	degraded := graph.Degraded()
+
+	// Degraded state changes without a new generation, so it's part of the tag
+	healthTag := "ok"
+	if degraded != nil {
+		healthTag = fmt.Sprintf("degraded%d", degraded.Attempts)
+	}
+	if s.notModified(w, r, snap, healthTag) {
+		return
+	}
+
+	beadsFile := graph.Source.File()
 	response := map[string]interface{}{
-		"status":      "ok",
+		"status":      healthStatus(snap, degraded),
 		"version":     s.version,
 		"beadsFile":   beadsFile,
-		"lastUpdated": s.graph.LastUpdated,
-		"totalBeads":  len(s.graph.Beads),
+		"backend":     graph.Source.Backend(),
+		"lastUpdated": snap.LastUpdated,
+		"totalBeads":  len(snap.Beads),
+		"generation":  snap.Generation,
 	}
+
+	var warnings []string
+
+	// Surface unresolved git conflicts prominently so they get fixed before 'bd sync'
+	if hunks := snap.GetMergeConflicts(); len(hunks) > 0 {
+		response["mergeConflicts"] = hunks
+		warnings = append(warnings,
+			fmt.Sprintf("%s has %d unresolved git merge conflict(s); the newer version of each bead is shown. Resolve them before running 'bd sync'.", beadsFile, len(hunks)))
+	}
+
+	// A rejected reload means everything above describes the previous data
+	if degraded != nil {
+		response["degraded"] = degraded
+		warnings = append(warnings,
+			fmt.Sprintf("Showing data from %s; the latest reload was rejected: %s", snap.LastUpdated.Format(time.RFC3339), degraded.Reason))
+	}
+
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+
 	jsonResponse(w, http.StatusOK, response)
 }
 
 // GET /api/epics
 func (s *Server) handleEpics(w http.ResponseWriter, r *http.Request) {
-	epics := s.graph.GetEpics()
+	snap := s.project(r).Graph.Snapshot()
+	if s.notModified(w, r, snap) {
+		return
+	}
+
+	epics := snap.GetEpics()
 	jsonResponse(w, http.StatusOK, map[string]interface{}{
 		"epics": epics,
 	})
 }
 
-// POST /api/agent-mode
+// GET /api/conflicts
+func (s *Server) handleConflicts(w http.ResponseWriter, r *http.Request) {
+	snap := s.project(r).Graph.Snapshot()
+	if s.notModified(w, r, snap) {
+		return
+	}
+
+	conflicts := snap.GetConflicts()
+	jsonResponse(w, http.StatusOK, map[string]interface{}{
+		"conflicts":      conflicts,
+		"total":          len(conflicts),
+		"mergeConflicts": snap.GetMergeConflicts(),
+	})
+}
+
+// GET /api/diagnostics
+func (s *Server) handleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	snap := s.project(r).Graph.Snapshot()
+	if s.notModified(w, r, snap) {
+		return
+	}
+
+	diag := snap.GetDiagnostics()
+	jsonResponse(w, http.StatusOK, map[string]interface{}{
+		"diagnostics": diag,
+		"total":       diag.Total(),
+	})
+}
+
+// POST /api/agent-mode - toggles every project; under
+// /api/projects/{name} only that one
 func (s *Server) handleAgentMode(w http.ResponseWriter, r *http.Request) {
 	// Limit request body to 1KB to prevent DoS
 	r.Body = http.MaxBytesReader(w, r.Body, 1024)
-	
+
 	var body struct {
 		Enabled bool `json:"enabled"`
 	}
@@ -190,9 +412,239 @@ func (s *Server) handleAgentMode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.SetAgentMode(body.Enabled)
+	s.setAgentMode(r, body.Enabled)
 
 	jsonResponse(w, http.StatusOK, map[string]bool{
 		"agentMode": body.Enabled,
 	})
 }
+
+// maxWriteBody limits the size of write request bodies
+const maxWriteBody = 1 << 20
+
+// decodeBody reads a JSON request body into v, rejecting unknown fields so a
+// typo doesn't silently do nothing. On failure it writes a 400 response.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxWriteBody)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		errorResponse(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// update runs fn as a write transaction on the project's data source. The
+// watcher reloads the graph and broadcasts the change as for any other edit;
+// without one, the graph is rebuilt here.
+func (p *Project) update(fn func(tx *beads.Tx) error) error {
+	src, ok := p.Graph.Source.(beads.WritableSource)
+	if !ok {
+		return beads.ErrReadOnly
+	}
+	if err := src.Update(fn); err != nil {
+		return err
+	}
+	if p.watcher == nil {
+		if err := p.Graph.Rebuild(); err != nil {
+			log.Printf("Error rebuilding graph after write: %v", err)
+		} else if p.onReload != nil {
+			p.onReload()
+		}
+	}
+	return nil
+}
+
+// writeErrorResponse maps an error from a write to an HTTP status
+func writeErrorResponse(w http.ResponseWriter, err error) {
+	var invalid *beads.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		errorResponse(w, http.StatusBadRequest, invalid.Error())
+	case errors.Is(err, beads.ErrBeadNotFound):
+		errorResponse(w, http.StatusNotFound, "Bead not found")
+	case errors.Is(err, beads.ErrMergeConflict):
+		errorResponse(w, http.StatusConflict, "Resolve the merge conflicts in the data file before editing")
+	case errors.Is(err, beads.ErrReadOnly):
+		errorResponse(w, http.StatusNotImplemented, "This data source is read-only")
+	default:
+		log.Printf("Write failed: %v", err)
+		errorResponse(w, http.StatusInternalServerError, "Failed to write beads")
+	}
+}
+
+// POST /api/beads
+func (s *Server) handleCreateBead(w http.ResponseWriter, r *http.Request) {
+	var input beads.NewBead
+	if !decodeBody(w, r, &input) {
+		return
+	}
+
+	var created *beads.Bead
+	err := s.project(r).update(func(tx *beads.Tx) error {
+		var err error
+		created, err = tx.Create(&input)
+		return err
+	})
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, created)
+}
+
+// PATCH /api/beads/{id}
+func (s *Server) handleUpdateBead(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var patch beads.BeadPatch
+	if !decodeBody(w, r, &patch) {
+		return
+	}
+
+	var updated *beads.Bead
+	err := s.project(r).update(func(tx *beads.Tx) error {
+		var err error
+		updated, err = tx.Update(id, &patch)
+		return err
+	})
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, updated)
+}
+
+// POST /api/beads/{id}/comments
+func (s *Server) handleAddComment(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var body struct {
+		Author string `json:"author"`
+		Text   string `json:"text"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	var comment *beads.Comment
+	err := s.project(r).update(func(tx *beads.Tx) error {
+		var err error
+		comment, err = tx.AddComment(id, body.Author, body.Text)
+		return err
+	})
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, comment)
+}
+
+// errDryRun aborts a bulk transaction after the results are known
+var errDryRun = errors.New("dry run")
+
+// bulkRequest selects beads by ID or filter and applies one patch to them all
+type bulkRequest struct {
+	IDs     []string          `json:"ids,omitempty"`
+	Filter  map[string]string `json:"filter,omitempty"` // Same keys as the /api/beads query
+	Changes beads.BeadPatch   `json:"changes"`
+	DryRun  bool              `json:"dryRun"`
+}
+
+// bulkResult reports what happened, or would happen, to one bead
+type bulkResult struct {
+	ID     string                 `json:"id"`
+	Result string                 `json:"result"` // "updated", "unchanged" or "failed"
+	Error  string                 `json:"error,omitempty"`
+	Fields map[string]interface{} `json:"fields,omitempty"` // New values of changed fields
+	Bead   *beads.Bead            `json:"bead,omitempty"`
+}
+
+// POST /api/beads/bulk
+//
+// Changes are all-or-nothing: if any bead fails, nothing is written and the
+// report says which ones failed. With dryRun the report is a preview.
+func (s *Server) handleBulkUpdate(w http.ResponseWriter, r *http.Request) {
+	p := s.project(r)
+
+	var req bulkRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	ids := req.IDs
+	switch {
+	case len(req.IDs) > 0 && len(req.Filter) > 0:
+		errorResponse(w, http.StatusBadRequest, "Use either ids or filter, not both")
+		return
+	case len(req.Filter) > 0:
+		// Filters need linked relationships (ready, blockers), so they run
+		// against the served snapshot rather than the raw file
+		query := url.Values{}
+		for key, value := range req.Filter {
+			query.Set(key, value)
+		}
+		for _, bead := range p.Graph.Snapshot().GetBeads(parseFilter(query)) {
+			ids = append(ids, bead.ID)
+		}
+	case len(req.IDs) == 0:
+		errorResponse(w, http.StatusBadRequest, "Select beads with a non-empty ids list or filter")
+		return
+	}
+
+	results := make([]*bulkResult, 0, len(ids))
+	failed := false
+	err := p.update(func(tx *beads.Tx) error {
+		for _, id := range ids {
+			result := &bulkResult{ID: id, Result: "unchanged"}
+			results = append(results, result)
+
+			before, err := tx.Get(id)
+			if err == nil {
+				result.Bead, err = tx.Update(id, &req.Changes)
+			}
+			if err != nil {
+				result.Result = "failed"
+				result.Error = err.Error()
+				result.Bead = nil
+				failed = true
+				continue
+			}
+			if fields := beads.ChangedFields(before, result.Bead); len(fields) > 0 {
+				result.Result = "updated"
+				result.Fields = fields
+			}
+		}
+		if failed || req.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	updated := 0
+	for _, result := range results {
+		if result.Result == "updated" {
+			updated++
+		}
+	}
+
+	status := http.StatusOK
+	if failed {
+		status = http.StatusUnprocessableEntity
+	}
+	jsonResponse(w, status, map[string]interface{}{
+		"dryRun":  req.DryRun,
+		"applied": !failed && !req.DryRun,
+		"matched": len(ids),
+		"updated": updated,
+		"results": results,
+	})
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taylorkpotter/seeBeads/internal/beads"
+)
+
+// newWritableHandler serves beads bd-1 to bd-n, all open, through the
+// embedded handler with writes enabled
+func newWritableHandler(t *testing.T, n int) (http.Handler, string) {
+	t.Helper()
+	var content strings.Builder
+	for i := 1; i <= n; i++ {
+		content.WriteString(strings.ReplaceAll(testBead, `"bd-1","title":"one"`, fmt.Sprintf(`"bd-%d","title":"bead %d"`, i, i)))
+	}
+	path := filepath.Join(t.TempDir(), "issues.jsonl")
+	if err := os.WriteFile(path, []byte(content.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	graph, err := beads.BuildGraph(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewHandler(graph, path, "", HandlerOptions{Writable: true}), path
+}
+
+// serve sends a request to handler and returns the recorded response
+func serve(handler http.Handler, method, target, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+type bulkResponse struct {
+	DryRun  bool         `json:"dryRun"`
+	Applied bool         `json:"applied"`
+	Matched int          `json:"matched"`
+	Updated int          `json:"updated"`
+	Results []bulkResult `json:"results"`
+}
+
+func postBulk(t *testing.T, handler http.Handler, body string, status int) bulkResponse {
+	t.Helper()
+	rec := serve(handler, http.MethodPost, "/api/beads/bulk", body, nil)
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, status, rec.Body)
+	}
+	var resp bulkResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestBulkUpdateDryRunLeavesFileUntouched(t *testing.T) {
+	handler, path := newWritableHandler(t, 3)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := postBulk(t, handler, `{"ids":["bd-1","bd-2"],"changes":{"status":"in_progress"},"dryRun":true}`, http.StatusOK)
+	if !resp.DryRun || resp.Applied || resp.Updated != 2 {
+		t.Errorf("response = %+v", resp)
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("dry run changed the file:\n%s", after)
+	}
+}
+
+func TestBulkUpdateIsAllOrNothing(t *testing.T) {
+	handler, path := newWritableHandler(t, 3)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := postBulk(t, handler, `{"ids":["bd-1","bd-404","bd-2"],"changes":{"priority":1}}`, http.StatusUnprocessableEntity)
+	if resp.Applied {
+		t.Error("reported as applied")
+	}
+	for _, result := range resp.Results {
+		if want := result.ID == "bd-404"; (result.Result == "failed") != want {
+			t.Errorf("%s: result = %q", result.ID, result.Result)
+		}
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("failed bulk update wrote the file:\n%s", after)
+	}
+}
+
+func TestBulkUpdateReport(t *testing.T) {
+	handler, path := newWritableHandler(t, 3)
+
+	resp := postBulk(t, handler, `{"filter":{"status":"open"},"changes":{"assignee":"sam","add_labels":["triaged"]}}`, http.StatusOK)
+	if !resp.Applied || resp.DryRun || resp.Matched != 3 || resp.Updated != 3 {
+		t.Errorf("response = %+v", resp)
+	}
+
+	resp = postBulk(t, handler, `{"ids":["bd-1","bd-3"],"changes":{"assignee":"sam","priority":1}}`, http.StatusOK)
+	if resp.Matched != 2 || resp.Updated != 2 || len(resp.Results) != 2 {
+		t.Fatalf("response = %+v", resp)
+	}
+	for _, result := range resp.Results {
+		if result.Result != "updated" || result.Bead == nil || result.Bead.Priority != 1 {
+			t.Errorf("%s: result = %+v", result.ID, result)
+		}
+		if _, ok := result.Fields["assignee"]; ok {
+			t.Errorf("%s: unchanged assignee reported in fields %v", result.ID, result.Fields)
+		}
+		if _, ok := result.Fields["priority"]; !ok {
+			t.Errorf("%s: priority missing from fields %v", result.ID, result.Fields)
+		}
+	}
+
+	resp = postBulk(t, handler, `{"ids":["bd-1"],"changes":{"priority":1}}`, http.StatusOK)
+	if resp.Updated != 0 || resp.Results[0].Result != "unchanged" {
+		t.Errorf("unchanged bead reported as %+v", resp.Results[0])
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(data), `"assignee":"sam"`); got != 3 {
+		t.Errorf("%d beads assigned in the file, want 3", got)
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	handler, _ := newWritableHandler(t, 2)
+	targets := []string{"/api/stats", "/api/beads", "/api/beads/bd-1", "/api/epics"}
+
+	etags := make(map[string]string)
+	for _, target := range targets {
+		rec := serve(handler, http.MethodGet, target, "", nil)
+		etag := rec.Header().Get("ETag")
+		if rec.Code != http.StatusOK || etag == "" {
+			t.Fatalf("%s: status = %d, ETag = %q", target, rec.Code, etag)
+		}
+		etags[target] = etag
+
+		rec = serve(handler, http.MethodGet, target, "", http.Header{"If-None-Match": {etag}})
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("%s: repeated GET status = %d, want 304", target, rec.Code)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("%s: 304 has a body: %s", target, rec.Body)
+		}
+	}
+
+	// The watcher rebuilds the graph after a write, bumping the generation
+	rec := serve(handler, http.MethodPatch, "/api/beads/bd-2", `{"title":"renamed"}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %d: %s", rec.Code, rec.Body)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for serve(handler, http.MethodGet, "/api/beads", "", nil).Header().Get("ETag") == etags["/api/beads"] {
+		if time.Now().After(deadline) {
+			t.Fatal("graph not rebuilt after the write")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	for _, target := range targets {
+		rec := serve(handler, http.MethodGet, target, "", http.Header{"If-None-Match": {etags[target]}})
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d after a rebuild, want 200", target, rec.Code)
+		}
+		if etag := rec.Header().Get("ETag"); etag == etags[target] || etag == "" {
+			t.Errorf("%s: ETag = %q after a rebuild, was %q", target, etag, etags[target])
+		}
+	}
+}
+
+func TestStatsETagMovesWithTime(t *testing.T) {
+	handler, _ := newWritableHandler(t, 1)
+	etag := serve(handler, http.MethodGet, "/api/stats", "", nil).Header().Get("ETag")
+	bucket := time.Now().Truncate(statsTagInterval).Unix()
+	suffix := fmt.Sprintf("-%d\"", bucket)
+	if !strings.HasSuffix(etag, suffix) {
+		t.Skipf("ETag %s is from another interval than %d", etag, bucket)
+	}
+
+	// A tag from the previous interval is out of date even without a rebuild
+	earlier := strings.TrimSuffix(etag, suffix) + fmt.Sprintf("-%d\"", bucket-int64(statsTagInterval/time.Second))
+	if rec := serve(handler, http.MethodGet, "/api/stats", "", http.Header{"If-None-Match": {earlier}}); rec.Code != http.StatusOK {
+		t.Errorf("status = %d for an earlier interval's ETag, want 200", rec.Code)
+	}
+
+	// If-Modified-Since can't see the interval, so it never gets a 304
+	since := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if rec := serve(handler, http.MethodGet, "/api/stats", "", http.Header{"If-Modified-Since": {since}}); rec.Code != http.StatusOK {
+		t.Errorf("status = %d for If-Modified-Since, want 200", rec.Code)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`W/"a-1"`, true},
+		{`"a-1"`, true},
+		{`W/"a-2"`, false},
+		{`W/"a-2", W/"a-1"`, true},
+		{`*`, true},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, `W/"a-1"`); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
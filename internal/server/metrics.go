@@ -0,0 +1,150 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// metricFamily is one metric in the Prometheus text exposition format, with
+// its samples across every project
+type metricFamily struct {
+	name    string
+	kind    string // counter, gauge or summary
+	help    string
+	samples []metricSample
+}
+
+type metricSample struct {
+	suffix string   // Appended to the family name, like "_sum" for summaries
+	labels []string // Name, value pairs
+	value  float64
+}
+
+// metricSet collects families in the order they are first added, since the
+// exposition format needs each family's samples written together
+type metricSet struct {
+	families []*metricFamily
+	byName   map[string]*metricFamily
+}
+
+func newMetricSet() *metricSet {
+	return &metricSet{byName: make(map[string]*metricFamily)}
+}
+
+// add records a sample of the named family
+func (m *metricSet) add(name, kind, help string, sample metricSample) {
+	family := m.byName[name]
+	if family == nil {
+		family = &metricFamily{name: name, kind: kind, help: help}
+		m.families = append(m.families, family)
+		m.byName[name] = family
+	}
+	family.samples = append(family.samples, sample)
+}
+
+func (m *metricSet) gauge(name, help string, value float64, labels ...string) {
+	m.add(name, "gauge", help, metricSample{labels: labels, value: value})
+}
+
+func (m *metricSet) counter(name, help string, value float64, labels ...string) {
+	m.add(name, "counter", help, metricSample{labels: labels, value: value})
+}
+
+// gaugeMap records one sample per map key, labeled by label, in key order
+func (m *metricSet) gaugeMap(name, help, label string, values map[string]int, labels ...string) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		m.gauge(name, help, float64(values[key]), append(labels[:len(labels):len(labels)], label, key)...)
+	}
+}
+
+// write renders every family in the text exposition format
+func (m *metricSet) write(buf *bytes.Buffer) {
+	for _, family := range m.families {
+		buf.WriteString("# HELP " + family.name + " " + family.help + "\n")
+		buf.WriteString("# TYPE " + family.name + " " + family.kind + "\n")
+		for _, sample := range family.samples {
+			buf.WriteString(family.name + sample.suffix)
+			if len(sample.labels) > 0 {
+				buf.WriteByte('{')
+				for i := 0; i+1 < len(sample.labels); i += 2 {
+					if i > 0 {
+						buf.WriteByte(',')
+					}
+					buf.WriteString(sample.labels[i] + `="` + escapeLabel(sample.labels[i+1]) + `"`)
+				}
+				buf.WriteByte('}')
+			}
+			buf.WriteString(" " + strconv.FormatFloat(sample.value, 'g', -1, 64) + "\n")
+		}
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a label value for the exposition format
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+// collect adds a project's bead counts and operational metrics
+func (m *metricSet) collect(p *Project) {
+	snap := p.Graph.Snapshot()
+	stats := snap.GetStats()
+	project := []string{"project", p.Name}
+
+	m.gauge("seebeads_beads", "Number of beads.", float64(stats.Total), project...)
+	m.gaugeMap("seebeads_beads_by_status", "Number of beads by status.", "status", stats.ByStatus, project...)
+	m.gaugeMap("seebeads_beads_by_type", "Number of beads by issue type.", "type", stats.ByType, project...)
+	m.gaugeMap("seebeads_beads_by_priority", "Number of beads by priority.", "priority", stats.ByPriority, project...)
+	m.gauge("seebeads_beads_blocked", "Number of unclosed beads with an open blocker.", float64(stats.Blocked), project...)
+	m.gauge("seebeads_beads_ready", "Number of beads ready to work on.", float64(stats.Ready), project...)
+	m.gauge("seebeads_beads_stale", "Number of unclosed beads not updated in 7 days.", float64(stats.Stale), project...)
+	m.gauge("seebeads_beads_created_7d", "Number of beads created in the last 7 days.", float64(stats.Velocity.Created7d), project...)
+	m.gauge("seebeads_beads_closed_7d", "Number of beads closed in the last 7 days.", float64(stats.Velocity.Closed7d), project...)
+
+	m.gauge("seebeads_parse_errors", "Number of lines that failed to parse in the latest load.", float64(len(snap.Errors)), project...)
+	degraded := 0.0
+	if p.Graph.Degraded() != nil {
+		degraded = 1
+	}
+	m.gauge("seebeads_degraded", "Whether the latest reload was rejected and previous data is being served.", degraded, project...)
+
+	reloads := p.Graph.ReloadStats()
+	m.counter("seebeads_reloads_total", "Number of reloads, including rejected ones.", float64(reloads.Reloads), project...)
+	m.counter("seebeads_reload_failures_total", "Number of reloads that failed or were rejected.", float64(reloads.Failures), project...)
+	m.add("seebeads_reload_duration_seconds", "summary", "Time spent reloading.",
+		metricSample{suffix: "_sum", labels: project, value: reloads.Duration.Seconds()})
+	m.add("seebeads_reload_duration_seconds", "summary", "Time spent reloading.",
+		metricSample{suffix: "_count", labels: project, value: float64(reloads.Reloads)})
+
+	m.gauge("seebeads_sse_clients", "Number of connected event stream clients.", float64(p.sse.Clients()), project...)
+	m.counter("seebeads_sse_dropped_events_total", "Number of events dropped because the broadcast buffer or a slow client was full.", float64(p.sse.Dropped()), project...)
+
+	var watcherErrors uint64
+	if p.watcher != nil {
+		watcherErrors = p.watcher.Errors()
+	}
+	m.counter("seebeads_watcher_errors_total", "Number of errors reported by the file watcher.", float64(watcherErrors), project...)
+}
+
+// GET /metrics - bead counts and server health for Prometheus, labeled by project
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	metrics := newMetricSet()
+	for _, p := range s.projects {
+		metrics.collect(p)
+	}
+
+	var buf bytes.Buffer
+	metrics.write(&buf)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
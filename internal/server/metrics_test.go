@@ -0,0 +1,214 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/taylorkpotter/seeBeads/internal/beads"
+	"github.com/taylorkpotter/seeBeads/internal/config"
+)
+
+var metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*`)
+
+// parseExposition checks text against the Prometheus text format: HELP and
+// TYPE at most once per family and before its samples, each family's samples
+// together, names and labels well formed and values numbers. It returns the
+// samples keyed by name and labels, with label values unescaped.
+func parseExposition(text string) (map[string]float64, error) {
+	samples := make(map[string]float64)
+	helps := make(map[string]bool)
+	types := make(map[string]string)
+	done := make(map[string]bool) // Families whose samples have ended
+	family := ""
+
+	if !strings.HasSuffix(text, "\n") {
+		return nil, fmt.Errorf("output doesn't end with a newline")
+	}
+	for n, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		fail := func(format string, args ...interface{}) error {
+			return fmt.Errorf("line %d %q: %s", n+1, line, fmt.Sprintf(format, args...))
+		}
+
+		if strings.HasPrefix(line, "#") {
+			fields := strings.SplitN(line, " ", 4)
+			if len(fields) < 4 || (fields[1] != "HELP" && fields[1] != "TYPE") {
+				return nil, fail("malformed comment")
+			}
+			name := fields[2]
+			if metricNameRe.FindString(name) != name {
+				return nil, fail("bad metric name")
+			}
+			if name != family {
+				if done[name] {
+					return nil, fail("family %s continued after another one", name)
+				}
+				if family != "" {
+					done[family] = true
+				}
+				family = name
+			}
+			if fields[1] == "HELP" {
+				if helps[name] {
+					return nil, fail("second HELP")
+				}
+				helps[name] = true
+				continue
+			}
+			if types[name] != "" {
+				return nil, fail("second TYPE")
+			}
+			switch fields[3] {
+			case "counter", "gauge", "summary", "histogram", "untyped":
+			default:
+				return nil, fail("unknown type")
+			}
+			types[name] = fields[3]
+			continue
+		}
+
+		name := metricNameRe.FindString(line)
+		rest := line[len(name):]
+		switch {
+		case name == "":
+			return nil, fail("no metric name")
+		case name == family:
+		case types[family] == "summary" && (name == family+"_sum" || name == family+"_count"):
+		default:
+			return nil, fail("sample outside its family %s", family)
+		}
+
+		var labels []string
+		if strings.HasPrefix(rest, "{") {
+			rest = rest[1:]
+			for !strings.HasPrefix(rest, "}") {
+				label := metricNameRe.FindString(rest)
+				if label == "" || !strings.HasPrefix(rest[len(label):], `="`) {
+					return nil, fail("bad label")
+				}
+				rest = rest[len(label)+2:]
+				var value strings.Builder
+				for {
+					if rest == "" {
+						return nil, fail("unterminated label value")
+					}
+					c := rest[0]
+					rest = rest[1:]
+					if c == '"' {
+						break
+					}
+					if c == '\n' {
+						return nil, fail("raw newline in label value")
+					}
+					if c == '\\' {
+						if rest == "" {
+							return nil, fail("unterminated escape")
+						}
+						switch rest[0] {
+						case '\\', '"':
+							value.WriteByte(rest[0])
+						case 'n':
+							value.WriteByte('\n')
+						default:
+							return nil, fail("bad escape \\%c", rest[0])
+						}
+						rest = rest[1:]
+						continue
+					}
+					value.WriteByte(c)
+				}
+				labels = append(labels, label+"="+value.String())
+				rest = strings.TrimPrefix(rest, ",")
+			}
+			rest = rest[1:]
+		}
+
+		if !strings.HasPrefix(rest, " ") {
+			return nil, fail("no value")
+		}
+		value, err := strconv.ParseFloat(rest[1:], 64)
+		if err != nil {
+			return nil, fail("bad value: %v", err)
+		}
+		sort.Strings(labels)
+		key := name + "{" + strings.Join(labels, ",") + "}"
+		if _, ok := samples[key]; ok {
+			return nil, fail("duplicate sample")
+		}
+		samples[key] = value
+	}
+	return samples, nil
+}
+
+func TestMetricsExposition(t *testing.T) {
+	odd := "q\"uote\\back\nline"
+	var projects []*Project
+	for i, name := range []string{"api", odd} {
+		path := filepath.Join(t.TempDir(), "issues.jsonl")
+		content := testBead + "not json\n"
+		if i == 1 {
+			content = testBead + strings.ReplaceAll(testBead, "bd-1", "bd-2")
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		graph, err := beads.BuildGraph(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		projects = append(projects, &Project{Name: name, Graph: graph})
+	}
+	cfg := config.DefaultConfig()
+	cfg.NoWatch = true
+	cfg.Metrics = true
+	handler := NewWorkspace(cfg, projects, "test").handler()
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Host = "127.0.0.1:3456"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+
+	samples, err := parseExposition(rec.Body.String())
+	if err != nil {
+		t.Fatalf("%v\n%s", err, rec.Body)
+	}
+
+	for _, project := range []string{"api", odd} {
+		want := map[string]float64{
+			"seebeads_beads":                         1,
+			"seebeads_parse_errors":                  0,
+			"seebeads_reload_duration_seconds_count": 0,
+			"seebeads_reloads_total":                 0,
+		}
+		if project == odd {
+			want["seebeads_beads"] = 2
+		} else {
+			want["seebeads_parse_errors"] = 1
+		}
+		for name, value := range want {
+			key := name + "{project=" + project + "}"
+			if got, ok := samples[key]; !ok || got != value {
+				t.Errorf("%q = %v (present %v), want %v", key, got, ok, value)
+			}
+		}
+		if _, ok := samples["seebeads_reload_duration_seconds_sum{project="+project+"}"]; !ok {
+			t.Errorf("no reload duration sum for %q", project)
+		}
+		if _, ok := samples["seebeads_beads_by_status{project="+project+",status=open}"]; !ok {
+			t.Errorf("no open bead count for %q", project)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/taylorkpotter/seeBeads/internal/beads"
+)
+
+// Project is one .beads directory served by a Server, with its own watcher
+// and event stream. Every project's API is served under
+// /api/projects/{name}; the first is also served at /api for the dashboard.
+type Project struct {
+	Name  string
+	Graph *beads.BeadsGraph
+
+	watcher  *beads.Watcher
+	sse      *SSEHub
+	onReload func() // Called after the graph reloads, to refresh the aggregate
+}
+
+type projectKey struct{}
+
+// project returns the project a request is for, the first one unless the
+// route names another
+func (s *Server) project(r *http.Request) *Project {
+	if p, ok := r.Context().Value(projectKey{}).(*Project); ok {
+		return p
+	}
+	return s.projects[0]
+}
+
+// setAgentMode toggles agent mode for the project named in the request's
+// path, or for every project when the request came in at /api
+func (s *Server) setAgentMode(r *http.Request, enabled bool) {
+	if p, ok := r.Context().Value(projectKey{}).(*Project); ok {
+		p.setAgentMode(enabled)
+		return
+	}
+	s.SetAgentMode(enabled)
+}
+
+// serveProject routes requests to one project
+func (s *Server) serveProject(p *Project) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), projectKey{}, p)))
+		})
+	}
+}
+
+// requireProject routes requests to the project named in the path,
+// responding 404 for unknown names
+func (s *Server) requireProject(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["project"]
+		p := s.byName[name]
+		if p == nil {
+			errorResponse(w, http.StatusNotFound, fmt.Sprintf("Project not found: %s", name))
+			return
+		}
+		s.serveProject(p)(next).ServeHTTP(w, r)
+	})
+}
+
+// setupAggregate merges every project into one read-only graph, where
+// dependencies between projects resolve. It's rebuilt whenever one of them
+// reloads.
+func (s *Server) setupAggregate(heartbeat time.Duration) {
+	graphs := make([]beads.ProjectGraph, len(s.projects))
+	for i, p := range s.projects {
+		graphs[i] = beads.ProjectGraph{Name: p.Name, Graph: p.Graph}
+	}
+	graph, err := beads.BuildAggregateGraph(graphs)
+	if err != nil {
+		log.Printf("Warning: could not merge projects: %v", err)
+		return
+	}
+
+	s.aggregate = &Project{Name: "all", Graph: graph, sse: NewSSEHub(heartbeat)}
+	for _, p := range s.projects {
+		p.onReload = s.refreshAggregate
+	}
+}
+
+// refreshAggregate rebuilds the merged graph after a project reloads. Each
+// project's reload has already passed its own reload policy, so the merge is
+// forced through.
+func (s *Server) refreshAggregate() {
+	s.mergeMu.Lock()
+	defer s.mergeMu.Unlock()
+
+	if err := s.aggregate.Graph.ForceRebuild(); err != nil {
+		log.Printf("Error merging projects: %v", err)
+		return
+	}
+	s.aggregate.broadcastReload()
+}
+
+// healthStatus summarizes a project's health as ok, warning (unresolved
+// merge conflicts) or degraded (the latest reload was rejected)
+func healthStatus(snap *beads.Snapshot, degraded *beads.DegradedError) string {
+	switch {
+	case degraded != nil:
+		return "degraded"
+	case len(snap.GetMergeConflicts()) > 0:
+		return "warning"
+	}
+	return "ok"
+}
+
+// GET /api/projects - every project with its stats and health
+func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
+	projects := make([]map[string]interface{}, 0, len(s.projects))
+	for _, p := range s.projects {
+		snap := p.Graph.Snapshot()
+		degraded := p.Graph.Degraded()
+		project := map[string]interface{}{
+			"name":        p.Name,
+			"url":         s.basePath + "/api/projects/" + p.Name,
+			"beadsFile":   p.Graph.Source.File(),
+			"backend":     p.Graph.Source.Backend(),
+			"status":      healthStatus(snap, degraded),
+			"stats":       snap.GetStats(),
+			"lastUpdated": snap.LastUpdated,
+			"generation":  snap.Generation,
+		}
+		if degraded != nil {
+			project["degraded"] = degraded
+		}
+		projects = append(projects, project)
+	}
+
+	response := map[string]interface{}{
+		"projects": projects,
+		"total":    len(projects),
+	}
+	// The aggregate entry needs the merged source's ID prefixes
+	if s.aggregate != nil {
+		if source, ok := s.aggregate.Graph.Source.(*beads.AggregateSource); ok {
+			response["aggregate"] = map[string]interface{}{
+				"url":      s.basePath + "/api/all",
+				"stats":    s.aggregate.Graph.Snapshot().GetStats(),
+				"prefixes": source.Prefixes(),
+			}
+		}
+	}
+	jsonResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/taylorkpotter/seeBeads/internal/beads"
+	"github.com/taylorkpotter/seeBeads/internal/config"
+)
+
+// newTestProjects returns a project per name, the nth with n beads whose IDs
+// are prefixed with the name: api-1, then web-1 and web-2, and so on
+func newTestProjects(t *testing.T, names ...string) []*Project {
+	t.Helper()
+	var projects []*Project
+	for i, name := range names {
+		content := ""
+		for n := 1; n <= i+1; n++ {
+			content += strings.ReplaceAll(testBead, "bd-1", fmt.Sprintf("%s-%d", name, n))
+		}
+		path := filepath.Join(t.TempDir(), "issues.jsonl")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		graph, err := beads.BuildGraph(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		projects = append(projects, &Project{Name: name, Graph: graph})
+	}
+	return projects
+}
+
+// newWorkspaceHandler serves projects together without watchers, accepting
+// httptest's default host
+func newWorkspaceHandler(projects []*Project) http.Handler {
+	cfg := config.DefaultConfig()
+	cfg.NoWatch = true
+	cfg.AllowedHosts = []string{"example.com"}
+	return NewWorkspace(cfg, projects, "test").handler()
+}
+
+func TestHandleProjects(t *testing.T) {
+	handler := newWorkspaceHandler(newTestProjects(t, "api", "web"))
+	rec := serve(handler, http.MethodGet, "/api/projects", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	var body struct {
+		Projects []struct {
+			Name  string `json:"name"`
+			URL   string `json:"url"`
+			Stats struct {
+				Total int `json:"total"`
+			} `json:"stats"`
+		} `json:"projects"`
+		Total     int `json:"total"`
+		Aggregate struct {
+			URL      string            `json:"url"`
+			Prefixes map[string]string `json:"prefixes"`
+		} `json:"aggregate"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range body.Projects {
+		got = append(got, fmt.Sprintf("%s %s %d", p.Name, p.URL, p.Stats.Total))
+	}
+	want := []string{"api /api/projects/api 1", "web /api/projects/web 2"}
+	if body.Total != 2 || strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("projects = %v (total %d), want %v", got, body.Total, want)
+	}
+	if body.Aggregate.URL != "/api/all" || body.Aggregate.Prefixes["web"] != "web" {
+		t.Errorf("aggregate = %+v", body.Aggregate)
+	}
+}
+
+func TestProjectRoutes(t *testing.T) {
+	handler := newWorkspaceHandler(newTestProjects(t, "api", "web"))
+
+	tests := []struct {
+		target string
+		status int
+		ids    string // Bead IDs listed, in any order, for /beads
+	}{
+		{"/api/beads", http.StatusOK, "api-1"},
+		{"/api/projects/api/beads", http.StatusOK, "api-1"},
+		{"/api/projects/web/beads", http.StatusOK, "web-1,web-2"},
+		{"/api/projects/web/beads/web-2", http.StatusOK, ""},
+		{"/api/projects/api/beads/web-2", http.StatusNotFound, ""},
+		{"/api/projects/nope/beads", http.StatusNotFound, ""},
+		{"/api/projects/nope/stats", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := serve(handler, http.MethodGet, tt.target, "", nil)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d: %s", tt.target, rec.Code, tt.status, rec.Body)
+			continue
+		}
+		if strings.Contains(tt.target, "/nope/") && !strings.Contains(rec.Body.String(), "Project not found: nope") {
+			t.Errorf("%s: body = %s", tt.target, rec.Body)
+		}
+		if tt.ids == "" {
+			continue
+		}
+		var body struct {
+			Beads []struct {
+				ID string `json:"id"`
+			} `json:"beads"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, bead := range body.Beads {
+			ids = append(ids, bead.ID)
+		}
+		sort.Strings(ids)
+		if got := strings.Join(ids, ","); got != tt.ids {
+			t.Errorf("%s: beads = %s, want %s", tt.target, got, tt.ids)
+		}
+	}
+}
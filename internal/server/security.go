@@ -0,0 +1,142 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/taylorkpotter/seeBeads/internal/config"
+)
+
+// contentSecurityPolicy allows the dashboard's own scripts, Google Fonts, the
+// GitHub release check and images linked from bead descriptions
+const contentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self'; " +
+	"style-src 'self' 'unsafe-inline' https://fonts.googleapis.com; " +
+	"font-src 'self' https://fonts.gstatic.com; " +
+	"img-src 'self' data: https:; " +
+	"connect-src 'self' https://api.github.com; " +
+	"object-src 'none'; " +
+	"base-uri 'self'; " +
+	"form-action 'self'; " +
+	"frame-ancestors 'self'"
+
+// requestGuard protects the server from other sites in the same browser.
+// Checking the Host header defeats DNS rebinding, where an attacker's domain
+// is pointed at 127.0.0.1; checking Origin stops cross-site pages from
+// making changes.
+type requestGuard struct {
+	checkHost bool
+	hosts     map[string]bool // Allowed Host names, lowercase and without port
+	origins   map[string]bool // Allowed origins besides the request's own
+}
+
+// newRequestGuard builds the guard for a standalone server. Besides the
+// configured hosts it accepts localhost, IP addresses, the bind host and this
+// machine's hostname; a rebinding attack always arrives with a domain name.
+func newRequestGuard(cfg *config.Config) *requestGuard {
+	g := &requestGuard{
+		checkHost: true,
+		hosts:     map[string]bool{"localhost": true},
+		origins:   make(map[string]bool),
+	}
+	names := append([]string{cfg.Host}, cfg.AllowedHosts...)
+	if name, err := os.Hostname(); err == nil {
+		names = append(names, name)
+	}
+	for _, name := range names {
+		if name == "*" {
+			g.checkHost = false
+		}
+		if name != "" {
+			g.hosts[strings.ToLower(name)] = true
+		}
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		g.origins[normalizeOrigin(origin)] = true
+	}
+	return g
+}
+
+// embeddedGuard is used when seeBeads is mounted in another application,
+// which owns its host names; only same-origin changes and those from origins
+// are allowed
+func embeddedGuard(origins []string) *requestGuard {
+	g := &requestGuard{origins: make(map[string]bool)}
+	for _, origin := range origins {
+		g.origins[normalizeOrigin(origin)] = true
+	}
+	return g
+}
+
+// normalizeOrigin lowercases an origin and drops any trailing slash
+func normalizeOrigin(origin string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(origin)), "/")
+}
+
+// hostAllowed reports whether the Host header names this server
+func (g *requestGuard) hostAllowed(r *http.Request) bool {
+	if !g.checkHost {
+		return true
+	}
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	if net.ParseIP(host) != nil || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	return g.hosts[host]
+}
+
+// originAllowed reports whether a request came from this server's own pages
+// or an allowed origin. Requests without an Origin header aren't from a
+// browser page, unless Fetch Metadata says otherwise.
+func (g *requestGuard) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return r.Header.Get("Sec-Fetch-Site") != "cross-site"
+	}
+	if g.origins[normalizeOrigin(origin)] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// changesState reports whether a request needs an Origin check. WebSocket
+// handshakes are GETs, but CORS doesn't protect them.
+func changesState(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return websocket.IsWebSocketUpgrade(r)
+	}
+	return true
+}
+
+// protect sets security headers on every response and rejects requests for
+// other host names and state changes from other origins
+func (s *Server) protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("Content-Security-Policy", contentSecurityPolicy)
+		header.Set("X-Frame-Options", "SAMEORIGIN")
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("Referrer-Policy", "no-referrer")
+
+		if !s.guard.hostAllowed(r) {
+			errorResponse(w, http.StatusForbidden, fmt.Sprintf("Host %q is not allowed; add it with --allowed-host", r.Host))
+			return
+		}
+		if changesState(r) && !s.guard.originAllowed(r) {
+			errorResponse(w, http.StatusForbidden, "Cross-origin request not allowed")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
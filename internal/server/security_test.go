@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/taylorkpotter/seeBeads/internal/config"
+)
+
+func TestRequestGuardHosts(t *testing.T) {
+	guard := newRequestGuard(&config.Config{Host: "0.0.0.0", AllowedHosts: []string{"Beads.Internal"}})
+	open := newRequestGuard(&config.Config{Host: "127.0.0.1", AllowedHosts: []string{"*"}})
+
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{"127.0.0.1:3456", true},
+		{"localhost:3456", true},
+		{"LOCALHOST", true},
+		{"[::1]:3456", true},
+		{"192.168.1.20:3456", true},
+		{"app.localhost:3456", true},
+		{"beads.internal:3456", true},
+		{"evil.example", false},
+		{"evil.example:3456", false},
+		{"localhost.evil.example", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Host = tt.host
+		if got := guard.hostAllowed(r); got != tt.allowed {
+			t.Errorf("hostAllowed(%q) = %v, want %v", tt.host, got, tt.allowed)
+		}
+		if !open.hostAllowed(r) {
+			t.Errorf("--allowed-host '*' refused %q", tt.host)
+		}
+	}
+}
+
+func TestRequestGuardOrigins(t *testing.T) {
+	guard := newRequestGuard(&config.Config{Host: "127.0.0.1", AllowedOrigins: []string{"HTTP://localhost:5173/"}})
+
+	tests := []struct {
+		name    string
+		origin  string
+		fetch   string
+		allowed bool
+	}{
+		{"same origin", "http://127.0.0.1:3456", "", true},
+		{"allowed origin", "http://localhost:5173", "", true},
+		{"other origin", "https://evil.example", "", false},
+		{"other port", "http://127.0.0.1:9999", "", false},
+		{"opaque origin", "null", "", false},
+		{"no origin", "", "", true},
+		{"no origin, same site", "", "same-origin", true},
+		{"no origin, cross site", "", "cross-site", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/api/beads", nil)
+		r.Host = "127.0.0.1:3456"
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if tt.fetch != "" {
+			r.Header.Set("Sec-Fetch-Site", tt.fetch)
+		}
+		if got := guard.originAllowed(r); got != tt.allowed {
+			t.Errorf("%s: originAllowed = %v, want %v", tt.name, got, tt.allowed)
+		}
+	}
+}
+
+func TestProtect(t *testing.T) {
+	s, _ := newTestServer(t)
+	handler := s.handler()
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		host   string
+		origin string
+		status int
+	}{
+		{"read", "GET", "/api/stats", "127.0.0.1:3456", "", http.StatusOK},
+		{"cross-origin read", "GET", "/api/stats", "127.0.0.1:3456", "https://evil.example", http.StatusOK},
+		{"rebound host", "GET", "/api/stats", "evil.example", "", http.StatusForbidden},
+		{"cross-origin write", "POST", "/api/agent-mode", "127.0.0.1:3456", "https://evil.example", http.StatusForbidden},
+		{"cross-origin websocket", "GET", "/api/ws", "127.0.0.1:3456", "https://evil.example", http.StatusForbidden},
+		{"same-origin write", "POST", "/api/agent-mode", "127.0.0.1:3456", "http://127.0.0.1:3456", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(`{"enabled":false}`))
+			req.Host = tt.host
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if strings.HasSuffix(tt.target, "/ws") {
+				req.Header.Set("Connection", "Upgrade")
+				req.Header.Set("Upgrade", "websocket")
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			for _, header := range []string{"Content-Security-Policy", "X-Frame-Options", "X-Content-Type-Options", "Referrer-Policy"} {
+				if rec.Header().Get(header) == "" {
+					t.Errorf("%s not set", header)
+				}
+			}
+		})
+	}
+}
@@ -3,13 +3,17 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"embed"
 	"fmt"
 	"io/fs"
 	"log"
 	"net"
 	"net/http"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -24,33 +28,77 @@ var staticFiles embed.FS
 // Server represents the seeBeads HTTP server
 type Server struct {
 	config     *config.Config
-	graph      *beads.BeadsGraph
-	watcher    *beads.Watcher
+	projects   []*Project // The first is also served at /api
+	byName     map[string]*Project
+	aggregate  *Project   // All projects merged, served at /api/all; nil for one project
+	mergeMu    sync.Mutex // Serializes aggregate refreshes
 	router     *mux.Router
 	httpServer *http.Server
-	sse        *SSEHub
 	basePath   string
 	version    string
+	instanceID string    // Distinguishes ETags across restarts
+	tokens     *tokenSet // nil when authentication is off
+	guard      *requestGuard
 }
 
 // New creates a new server instance
 func New(cfg *config.Config, graph *beads.BeadsGraph, version string) *Server {
+	return NewWorkspace(cfg, []*Project{{Name: config.ProjectName(cfg.BeadsPath), Graph: graph}}, version)
+}
+
+// NewWorkspace creates a server for several projects, each with its own
+// routes under /api/projects/{name}. The first is also served at /api, and
+// with more than one they are merged into a read-only view at /api/all.
+func NewWorkspace(cfg *config.Config, projects []*Project, version string) *Server {
 	s := &Server{
-		config:   cfg,
-		graph:    graph,
-		router:   mux.NewRouter(),
-		sse:      NewSSEHub(),
-		basePath: "",
-		version:  version,
+		config:     cfg,
+		router:     mux.NewRouter(),
+		basePath:   "",
+		version:    version,
+		instanceID: newInstanceID(),
+		guard:      newRequestGuard(cfg),
+	}
+	for _, p := range projects {
+		s.addProject(p, cfg.HeartbeatInterval)
+	}
+	if len(projects) > 1 {
+		s.setupAggregate(cfg.HeartbeatInterval)
 	}
 
 	s.setupRoutes()
 	return s
 }
 
+// addProject registers a project and gives it an event hub
+func (s *Server) addProject(p *Project, heartbeat time.Duration) {
+	p.sse = NewSSEHub(heartbeat)
+	if s.byName == nil {
+		s.byName = make(map[string]*Project)
+	}
+	s.projects = append(s.projects, p)
+	s.byName[p.Name] = p
+}
+
+// newInstanceID returns a short ID that is unique per server start, so cached
+// ETags from a previous run never match the same generation number
+func newInstanceID() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// HandlerOptions configures an embedded handler
+type HandlerOptions struct {
+	// Writable registers the routes that create and edit beads. The embedded
+	// handler has no authentication of its own, so they are off by default.
+	Writable bool
+
+	// AllowedOrigins may make cross-origin requests and changes, besides the
+	// host application's own origin
+	AllowedOrigins []string
+}
+
 // NewHandler creates an http.Handler for embedding seeBeads in another application.
 // basePath is the URL prefix where the handler is mounted (e.g., "/beads").
-func NewHandler(graph *beads.BeadsGraph, jsonlPath, basePath string) http.Handler {
+func NewHandler(graph *beads.BeadsGraph, jsonlPath, basePath string, opts HandlerOptions) http.Handler {
 	// Normalize basePath
 	basePath = strings.TrimSuffix(basePath, "/")
 	if basePath != "" && !strings.HasPrefix(basePath, "/") {
@@ -62,77 +110,108 @@ func NewHandler(graph *beads.BeadsGraph, jsonlPath, basePath string) http.Handle
 			JSONLPath: jsonlPath,
 			NoWatch:   false,
 		},
-		graph:    graph,
-		router:   mux.NewRouter(),
-		sse:      NewSSEHub(),
-		basePath: basePath,
+		router:     mux.NewRouter(),
+		basePath:   basePath,
+		instanceID: newInstanceID(),
+		guard:      embeddedGuard(opts.AllowedOrigins),
 	}
+	p := &Project{Name: config.ProjectName(filepath.Dir(jsonlPath)), Graph: graph}
+	s.addProject(p, 0)
 
-	s.setupEmbeddedRoutes()
+	s.setupEmbeddedRoutes(opts.Writable)
 
 	// Start SSE hub
-	go s.sse.Run()
+	go p.sse.Run()
 
 	// Start file watcher
 	var err error
-	s.watcher, err = beads.NewWatcher(beads.WatcherConfig{
-		FilePath:  jsonlPath,
-		Graph:     graph,
-		AgentMode: false,
-		OnChange: func() {
-			// Broadcast reload event to trigger full data refetch
-			s.sse.Broadcast(SSEEvent{
-				Type: "reload",
-				Data: map[string]interface{}{
-					"timestamp": time.Now().Format(time.RFC3339),
-					"stats":     graph.GetStats(),
-				},
-			})
-		},
-	})
+	p.watcher, err = p.newWatcher(false)
 	if err == nil {
-		s.watcher.Start()
+		p.watcher.Start()
 	}
 
 	// Wrap with CORS - same-origin by default for security
 	// When embedded, the dashboard is served from the same origin as the host app
 	c := cors.New(cors.Options{
-		AllowedMethods: []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders: []string{"Content-Type"},
+		// An empty AllowedOrigins would allow every origin
+		AllowOriginFunc: func(origin string) bool { return s.guard.origins[normalizeOrigin(origin)] },
+		AllowedMethods:  []string{"GET", "POST", "PATCH", "OPTIONS"},
+		AllowedHeaders:  []string{"Content-Type"},
 	})
 
-	return c.Handler(s.router)
+	return s.protect(c.Handler(s.router))
 }
 
-func (s *Server) setupEmbeddedRoutes() {
+func (s *Server) setupEmbeddedRoutes(writable bool) {
 	// API routes under basePath
 	apiPrefix := s.basePath + "/api"
 	api := s.router.PathPrefix(apiPrefix).Subrouter()
-	api.HandleFunc("/stats", s.handleStats).Methods("GET")
-	api.HandleFunc("/beads", s.handleBeads).Methods("GET")
-	api.HandleFunc("/beads/{id}", s.handleBead).Methods("GET")
-	api.HandleFunc("/events", s.handleSSE).Methods("GET")
-	api.HandleFunc("/health", s.handleHealth).Methods("GET")
-	api.HandleFunc("/epics", s.handleEpics).Methods("GET")
-	api.HandleFunc("/agent-mode", s.handleAgentMode).Methods("POST")
+	api.HandleFunc("/projects", s.handleProjects).Methods("GET")
+	projects := api.PathPrefix("/projects/{project}").Subrouter()
+	projects.Use(s.requireProject)
+	s.setupProjectRoutes(projects, writable)
+	s.setupProjectRoutes(api, writable)
 
 	// Serve static files at basePath
-	s.router.PathPrefix(s.basePath).Handler(s.embeddedStaticHandler())
+	s.router.PathPrefix(s.basePath).Methods("GET", "HEAD").Handler(s.embeddedStaticHandler())
 }
 
 func (s *Server) setupRoutes() {
 	// API routes
 	api := s.router.PathPrefix("/api").Subrouter()
+	api.HandleFunc("/login", s.handleLogin).Methods("POST")
+	api.HandleFunc("/logout", s.handleLogout).Methods("POST")
+	api.HandleFunc("/projects", s.handleProjects).Methods("GET")
+	projects := api.PathPrefix("/projects/{project}").Subrouter()
+	projects.Use(s.requireProject)
+	s.setupProjectRoutes(projects, true)
+	s.setupProjectRoutes(api, true)
+
+	if s.aggregate != nil {
+		all := api.PathPrefix("/all").Subrouter()
+		all.Use(s.serveProject(s.aggregate))
+		s.setupReadRoutes(all)
+	}
+
+	if s.config.Metrics {
+		s.router.HandleFunc("/metrics", s.handleMetrics).Methods("GET")
+	}
+
+	// Serve static files (embedded React app)
+	s.router.PathPrefix("/").Methods("GET", "HEAD").Handler(s.staticHandler())
+}
+
+// setupProjectRoutes adds the routes served for each project, at /api for the
+// first and /api/projects/{project} for all of them. Routes that change
+// beads are only added if writable.
+func (s *Server) setupProjectRoutes(api *mux.Router, writable bool) {
+	s.setupReadRoutes(api)
+	api.HandleFunc("/agent-mode", s.handleAgentMode).Methods("POST")
+	if writable {
+		s.setupWriteRoutes(api)
+	}
+}
+
+// setupReadRoutes adds the routes that only read a project
+func (s *Server) setupReadRoutes(api *mux.Router) {
 	api.HandleFunc("/stats", s.handleStats).Methods("GET")
+	api.HandleFunc("/stats/series", s.handleStatsSeries).Methods("GET")
 	api.HandleFunc("/beads", s.handleBeads).Methods("GET")
 	api.HandleFunc("/beads/{id}", s.handleBead).Methods("GET")
 	api.HandleFunc("/events", s.handleSSE).Methods("GET")
+	api.HandleFunc("/ws", s.handleWebSocket).Methods("GET")
 	api.HandleFunc("/health", s.handleHealth).Methods("GET")
 	api.HandleFunc("/epics", s.handleEpics).Methods("GET")
-	api.HandleFunc("/agent-mode", s.handleAgentMode).Methods("POST")
+	api.HandleFunc("/conflicts", s.handleConflicts).Methods("GET")
+	api.HandleFunc("/diagnostics", s.handleDiagnostics).Methods("GET")
+}
 
-	// Serve static files (embedded React app)
-	s.router.PathPrefix("/").Handler(s.staticHandler())
+// setupWriteRoutes adds the routes that create and edit beads
+func (s *Server) setupWriteRoutes(api *mux.Router) {
+	api.HandleFunc("/beads", s.handleCreateBead).Methods("POST")
+	api.HandleFunc("/beads/bulk", s.handleBulkUpdate).Methods("POST")
+	api.HandleFunc("/beads/{id}", s.handleUpdateBead).Methods("PATCH")
+	api.HandleFunc("/beads/{id}/comments", s.handleAddComment).Methods("POST")
 }
 
 func (s *Server) staticHandler() http.Handler {
@@ -205,51 +284,127 @@ func (s *Server) embeddedStaticHandler() http.Handler {
 	})
 }
 
-// Start starts the HTTP server
-func (s *Server) Start() error {
-	// Set up file watcher
-	if !s.config.NoWatch {
-		var err error
-		s.watcher, err = beads.NewWatcher(beads.WatcherConfig{
-			FilePath:  s.config.JSONLPath,
-			Graph:     s.graph,
-			AgentMode: s.config.AgentMode,
+// newWatcher creates a file watcher that pushes the project's graph changes
+// to its SSE clients
+func (p *Project) newWatcher(agentMode bool) (*beads.Watcher, error) {
+	return beads.NewWatcher(beads.WatcherConfig{
+		Graph:     p.Graph,
+		AgentMode: agentMode,
 		OnChange: func() {
-			// Broadcast reload event to trigger full data refetch
-			s.sse.Broadcast(SSEEvent{
-				Type: "reload",
+			p.broadcastReload()
+			if p.onReload != nil {
+				p.onReload()
+			}
+		},
+		OnDegraded: func(degraded *beads.DegradedError) {
+			// Clients keep showing the previous data; let them flag it as stale
+			p.sse.Broadcast(SSEEvent{
+				Type:       "degraded",
+				Generation: p.Graph.Generation(),
 				Data: map[string]interface{}{
 					"timestamp": time.Now().Format(time.RFC3339),
-					"stats":     s.graph.GetStats(),
+					"degraded":  degraded,
 				},
 			})
 		},
-		})
-		if err != nil {
-			log.Printf("Warning: file watching disabled: %v", err)
-		} else {
-			if err := s.watcher.Start(); err != nil {
-				log.Printf("Warning: could not start file watcher: %v", err)
-			}
+		OnRecovered: func() {
+			p.sse.Broadcast(SSEEvent{
+				Type:       "recovered",
+				Generation: p.Graph.Generation(),
+				Data: map[string]interface{}{
+					"timestamp": time.Now().Format(time.RFC3339),
+				},
+			})
+		},
+	})
+}
+
+// broadcastReload sends the events for the graph's latest generation: one
+// typed event per change, then a "reload" telling clients to refetch. The
+// first generation and batches too large to replay only send the reload.
+// After typed events the reload carries "typed", the number sent, so clients
+// that applied them can skip the refetch; older dashboards still refetch.
+func (p *Project) broadcastReload() {
+	snap := p.Graph.Snapshot()
+	typed := 0
+	if snap.Generation > 1 && len(snap.Changes) <= maxChangeEvents {
+		for _, change := range snap.Changes {
+			p.sse.Broadcast(SSEEvent{Type: string(change.Type), Data: change, Generation: snap.Generation})
 		}
+		typed = len(snap.Changes)
+	} else if snap.Generation > 1 {
+		log.Printf("%s: %d changes in generation %d, sending reload only", p.Name, len(snap.Changes), snap.Generation)
 	}
 
-	// Set up CORS for development
-	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:5173", "http://localhost:3456", "http://127.0.0.1:5173", "http://127.0.0.1:3456"},
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type"},
-		AllowCredentials: true,
+	p.sse.Broadcast(SSEEvent{
+		Type:       "reload",
+		Generation: snap.Generation,
+		Data: map[string]interface{}{
+			"timestamp":  time.Now().Format(time.RFC3339),
+			"stats":      snap.GetStats(),
+			"generation": snap.Generation,
+			"changes":    len(snap.Changes),
+			"typed":      typed,
+		},
 	})
+}
+
+// maxChangeEvents caps the typed change events sent for one reload. Larger
+// batches (a branch switch, a bulk import) only send "reload", since clients
+// are better off refetching than replaying thousands of events.
+const maxChangeEvents = 50
 
-	handler := c.Handler(s.router)
+// Start starts the HTTP server
+func (s *Server) Start() error {
+	// Set up a file watcher per project
+	if !s.config.NoWatch {
+		for _, p := range s.projects {
+			var err error
+			p.watcher, err = p.newWatcher(s.config.AgentMode)
+			if err != nil {
+				log.Printf("Warning: file watching disabled for %s: %v", p.Name, err)
+			} else {
+				if err := p.watcher.Start(); err != nil {
+					log.Printf("Warning: could not start file watcher for %s: %v", p.Name, err)
+				}
+			}
+		}
+	}
+
+	// Require tokens if a tokens file is configured. Anyone who can reach a
+	// server beyond this machine could otherwise edit beads, so that takes
+	// tokens or an explicit --no-auth.
+	if s.config.TokensPath != "" {
+		tokens, err := loadTokens(s.config.TokensPath)
+		if err != nil {
+			return fmt.Errorf("failed to load tokens: %w", err)
+		}
+		s.tokens = tokens
+		log.Printf("Authentication enabled (%d tokens)", len(tokens.tokens))
+	} else if !isLoopback(s.config.Host) {
+		if !s.config.NoAuth {
+			return fmt.Errorf("refusing to serve on %s without authentication: add a tokens file, or pass --no-auth if the network is trusted", s.config.Host)
+		}
+		log.Printf("Warning: serving on %s without authentication (--no-auth)", s.config.Host)
+	}
+
+	// Load the certificate before binding so a bad one fails fast
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if s.config.UsesTLS() {
+		cert, err := s.tlsCertificate()
+		if err != nil {
+			return fmt.Errorf("failed to set up TLS: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
 
 	s.httpServer = &http.Server{
 		Addr:         s.config.Address(),
-		Handler:      handler,
+		Handler:      s.handler(),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		TLSConfig:    tlsConfig,
 	}
 
 	// Try to bind to the port, increment if busy
@@ -269,26 +424,68 @@ func (s *Server) Start() error {
 	}
 
 	log.Printf("seeBeads server starting on %s", s.config.URL())
-	log.Printf("Watching: %s", s.config.JSONLPath)
+	for _, p := range s.projects {
+		log.Printf("Watching %s: %s", p.Name, p.Graph.Source.Describe())
 
-	// Start SSE heartbeat
-	go s.sse.Run()
+		// Start SSE heartbeat
+		go p.sse.Run()
+	}
+	if s.aggregate != nil {
+		go s.aggregate.sse.Run()
+	}
 
+	if s.config.UsesTLS() {
+		return s.httpServer.ServeTLS(listener, "", "")
+	}
 	return s.httpServer.Serve(listener)
 }
 
+// handler wraps the router in the standalone server's middleware
+func (s *Server) handler() http.Handler {
+	// Set up CORS for the configured origins, like the web dev server
+	c := cors.New(cors.Options{
+		AllowedOrigins:   s.config.AllowedOrigins,
+		AllowedMethods:   []string{"GET", "POST", "PATCH", "OPTIONS"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowCredentials: true,
+	})
+
+	return s.protect(c.Handler(s.requireAuth(s.router)))
+}
+
+// isLoopback reports whether host only accepts local connections
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 // Stop gracefully shuts down the server
 func (s *Server) Stop(ctx context.Context) error {
-	if s.watcher != nil {
-		s.watcher.Stop()
+	for _, p := range s.projects {
+		if p.watcher != nil {
+			p.watcher.Stop()
+		}
+		p.sse.Stop()
+	}
+	if s.aggregate != nil {
+		s.aggregate.sse.Stop()
 	}
-	s.sse.Stop()
 	return s.httpServer.Shutdown(ctx)
 }
 
-// SetAgentMode toggles agent mode
+// SetAgentMode toggles agent mode for every project
 func (s *Server) SetAgentMode(enabled bool) {
-	if s.watcher != nil {
-		s.watcher.SetAgentMode(enabled)
+	for _, p := range s.projects {
+		p.setAgentMode(enabled)
+	}
+}
+
+// setAgentMode toggles agent mode for the project's watcher
+func (p *Project) setAgentMode(enabled bool) {
+	if p.watcher != nil {
+		p.watcher.SetAgentMode(enabled)
 	}
 }
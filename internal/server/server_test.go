@@ -0,0 +1,162 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/taylorkpotter/seeBeads/internal/beads"
+	"github.com/taylorkpotter/seeBeads/internal/config"
+)
+
+const testBead = `{"id":"bd-1","title":"one","status":"open","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}` + "\n"
+
+// newTestServer serves a one-bead project through the standalone server's
+// middleware. The SSE hub runs until the test ends.
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), ".beads")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "issues.jsonl")
+	if err := os.WriteFile(path, []byte(testBead), 0644); err != nil {
+		t.Fatal(err)
+	}
+	graph, err := beads.BuildGraph(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.BeadsPath = dir
+	cfg.JSONLPath = path
+	cfg.NoWatch = true
+	s := New(cfg, graph, "test")
+	p := s.projects[0]
+	go p.sse.Run()
+	t.Cleanup(p.sse.Stop)
+
+	ts := httptest.NewServer(s.handler())
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func TestNewHandlerWritable(t *testing.T) {
+	tests := []struct {
+		name     string
+		writable bool
+		status   int
+	}{
+		{"read-only by default", false, http.StatusMethodNotAllowed},
+		{"writable", true, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "issues.jsonl")
+			if err := os.WriteFile(path, []byte(testBead), 0644); err != nil {
+				t.Fatal(err)
+			}
+			graph, err := beads.BuildGraph(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			handler := NewHandler(graph, path, "/beads", HandlerOptions{Writable: tt.writable})
+
+			req := httptest.NewRequest(http.MethodPost, "/beads/api/beads", strings.NewReader(`{"title":"two"}`))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("POST status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+
+			req = httptest.NewRequest(http.MethodGet, "/beads/api/beads", nil)
+			req.Header.Set("Origin", "https://other.example")
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Errorf("GET status = %d", rec.Code)
+			}
+			if origin := rec.Header().Get("Access-Control-Allow-Origin"); origin != "" {
+				t.Errorf("CORS allows %q", origin)
+			}
+		})
+	}
+}
+
+func TestStartRefusesOpenHostsWithoutTokens(t *testing.T) {
+	for _, host := range []string{"0.0.0.0", "", "192.0.2.1", "::"} {
+		s, _ := newTestServer(t)
+		s.config.Host = host
+		err := s.Start()
+		if err == nil || !strings.Contains(err.Error(), "without authentication") {
+			t.Errorf("host %q: err = %v", host, err)
+		}
+	}
+}
+
+func TestAggregateRoutesAreReadOnly(t *testing.T) {
+	handler := newWorkspaceHandler(newTestProjects(t, "api", "web"))
+
+	tests := []struct {
+		method string
+		target string
+		status int
+	}{
+		{"GET", "/api/all/stats", http.StatusOK},
+		{"GET", "/api/all/beads", http.StatusOK},
+		{"POST", "/api/all/agent-mode", http.StatusMethodNotAllowed},
+		{"POST", "/api/all/beads", http.StatusMethodNotAllowed},
+		{"PATCH", "/api/all/beads/api-1", http.StatusMethodNotAllowed},
+		{"POST", "/api/projects/web/agent-mode", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(`{"enabled":false,"title":"two"}`))
+		req.Host = "127.0.0.1:3456"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: status = %d, want %d: %s", tt.method, tt.target, rec.Code, tt.status, rec.Body)
+		}
+	}
+}
+
+func TestAgentModeScope(t *testing.T) {
+	tests := []struct {
+		target string
+		want   map[string]bool
+	}{
+		{"/api/agent-mode", map[string]bool{"api": true, "web": true}},
+		{"/api/projects/web/agent-mode", map[string]bool{"api": false, "web": true}},
+		{"/api/projects/api/agent-mode", map[string]bool{"api": true, "web": false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			projects := newTestProjects(t, "api", "web")
+			for _, p := range projects {
+				var err error
+				if p.watcher, err = p.newWatcher(false); err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { p.watcher.Stop() })
+			}
+			handler := newWorkspaceHandler(projects)
+
+			req := httptest.NewRequest("POST", tt.target, strings.NewReader(`{"enabled":true}`))
+			req.Host = "127.0.0.1:3456"
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			for _, p := range projects {
+				if got := p.watcher.AgentMode(); got != tt.want[p.Name] {
+					t.Errorf("%s: agent mode = %v, want %v", p.Name, got, tt.want[p.Name])
+				}
+			}
+		})
+	}
+}
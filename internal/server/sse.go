@@ -5,46 +5,96 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/taylorkpotter/seeBeads/internal/beads"
+)
+
+const (
+	// replayBufferSize is how many recent events the hub keeps for clients
+	// that reconnect with Last-Event-ID
+	replayBufferSize = 1024
+
+	// defaultHeartbeat is used when no heartbeat interval is configured
+	defaultHeartbeat = 30 * time.Second
 )
 
+// streamWriteTimeout bounds each write to an event stream. Streams run far
+// longer than the server's WriteTimeout, so they set deadlines per write.
+// A variable so tests can stall a client without waiting this long.
+var streamWriteTimeout = 10 * time.Second
+
 // SSEEvent represents an event to be sent to clients
 type SSEEvent struct {
 	Type string      `json:"type"`
 	Data interface{} `json:"data"`
+
+	// Generation is the graph generation the event belongs to. The hub uses it
+	// to assign ID; events that aren't broadcast, like heartbeats, have neither.
+	Generation uint64 `json:"-"`
+	ID         string `json:"-"`
 }
 
 // SSEClient represents a connected SSE client
 type SSEClient struct {
 	id     string
 	events chan SSEEvent
+	filter *beads.Filter // Limits which change events are sent; nil for all
+	behind atomic.Bool   // Set when an event was dropped because events was full
+}
+
+// wants reports whether an event should be sent to the client. Only bead
+// change events are filtered; everything else goes to every client.
+func (c *SSEClient) wants(event SSEEvent) bool {
+	change, ok := event.Data.(*beads.Change)
+	if !ok || c.filter == nil {
+		return true
+	}
+	return change.Matches(c.filter)
 }
 
 // SSEHub manages SSE connections
 type SSEHub struct {
-	clients    map[string]*SSEClient
-	register   chan *SSEClient
-	unregister chan *SSEClient
-	broadcast  chan SSEEvent
-	stop       chan struct{}
-	mu         sync.RWMutex
+	clients   map[string]*SSEClient
+	broadcast chan SSEEvent
+	heartbeat time.Duration
+	stop      chan struct{}
+	stopOnce  sync.Once
+	stopped   bool // Set by Stop; guarded by mu
+	mu        sync.RWMutex
+
+	// Event IDs are "<epoch>-<generation>-<seq>". The epoch changes on every
+	// start so IDs from a previous run never match.
+	epoch   string
+	lastGen uint64
+	seq     int
+	history []SSEEvent // Ring of recent events, oldest at head
+	head    int
+
+	dropped atomic.Uint64 // Events dropped because the broadcast buffer or a client was full
 }
 
-// NewSSEHub creates a new SSE hub
-func NewSSEHub() *SSEHub {
+// NewSSEHub creates a new SSE hub that sends a heartbeat to every client at
+// the given interval, or every 30 seconds if it's zero
+func NewSSEHub(heartbeat time.Duration) *SSEHub {
+	if heartbeat <= 0 {
+		heartbeat = defaultHeartbeat
+	}
 	return &SSEHub{
-		clients:    make(map[string]*SSEClient),
-		register:   make(chan *SSEClient),
-		unregister: make(chan *SSEClient),
-		broadcast:  make(chan SSEEvent, 256),
-		stop:       make(chan struct{}),
+		clients:   make(map[string]*SSEClient),
+		broadcast: make(chan SSEEvent, 256),
+		heartbeat: heartbeat,
+		stop:      make(chan struct{}),
+		epoch:     newInstanceID(),
 	}
 }
 
 // Run starts the SSE hub event loop
 func (h *SSEHub) Run() {
-	heartbeat := time.NewTicker(30 * time.Second)
+	heartbeat := time.NewTicker(h.heartbeat)
 	defer heartbeat.Stop()
 
 	for {
@@ -52,31 +102,16 @@ func (h *SSEHub) Run() {
 		case <-h.stop:
 			return
 
-		case client := <-h.register:
-			h.mu.Lock()
-			h.clients[client.id] = client
-			h.mu.Unlock()
-			log.Printf("SSE client connected: %s (total: %d)", client.id, len(h.clients))
-
-		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client.id]; ok {
-				delete(h.clients, client.id)
-				close(client.events)
-			}
-			h.mu.Unlock()
-			log.Printf("SSE client disconnected: %s (total: %d)", client.id, len(h.clients))
-
 		case event := <-h.broadcast:
-			h.mu.RLock()
+			h.mu.Lock()
+			event.ID = h.nextID(event.Generation)
+			h.remember(event)
 			for _, client := range h.clients {
-				select {
-				case client.events <- event:
-				default:
-					// Client buffer full, skip
+				if client.wants(event) && !client.send(event) {
+					h.dropped.Add(1)
 				}
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
 
 		case <-heartbeat.C:
 			event := SSEEvent{
@@ -97,9 +132,134 @@ func (h *SSEHub) Run() {
 	}
 }
 
-// Stop stops the SSE hub
+// send queues an event for the client, flagging it as behind if it's full.
+// It reports whether the event was queued.
+func (c *SSEClient) send(event SSEEvent) bool {
+	select {
+	case c.events <- event:
+		return true
+	default:
+		c.behind.Store(true)
+		return false
+	}
+}
+
+// nextID assigns the next event ID within a generation.
+// Callers must hold the write lock.
+func (h *SSEHub) nextID(generation uint64) string {
+	if generation == h.lastGen {
+		h.seq++
+	} else {
+		h.lastGen = generation
+		h.seq = 0
+	}
+	return fmt.Sprintf("%s-%d-%d", h.epoch, generation, h.seq)
+}
+
+// remember adds an event to the replay ring. Callers must hold the write lock.
+func (h *SSEHub) remember(event SSEEvent) {
+	if len(h.history) < replayBufferSize {
+		h.history = append(h.history, event)
+		return
+	}
+	h.history[h.head] = event
+	h.head = (h.head + 1) % replayBufferSize
+}
+
+// lastID returns the ID of the newest event, or "" if there is none yet.
+// Callers must hold a lock.
+func (h *SSEHub) lastID() string {
+	if len(h.history) == 0 {
+		return ""
+	}
+	return h.history[(h.head+len(h.history)-1)%len(h.history)].ID
+}
+
+// since returns the events after the one with the given ID, oldest first.
+// ok is false if that event is no longer (or never was) in the ring.
+// Callers must hold a lock.
+func (h *SSEHub) since(id string) (events []SSEEvent, ok bool) {
+	for i := 0; i < len(h.history); i++ {
+		event := h.history[(h.head+i)%len(h.history)]
+		if ok {
+			events = append(events, event)
+		} else if event.ID == id {
+			ok = true
+		}
+	}
+	return events, ok
+}
+
+// subscribe registers a client and returns the events it missed since
+// lastEventID, atomically with respect to broadcasts so nothing is sent twice
+// or lost in between. resync is true if the missed events can't be replayed.
+// latest is the newest event ID, for a fresh client to resume from.
+func (h *SSEHub) subscribe(client *SSEClient, lastEventID string) (replay []SSEEvent, resync bool, latest string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if lastEventID != "" && lastEventID != h.lastID() {
+		missed, ok := h.since(lastEventID)
+		for _, event := range missed {
+			if client.wants(event) {
+				replay = append(replay, event)
+			}
+		}
+		resync = !ok
+	}
+	latest = h.lastID()
+
+	// A stopped hub ends the stream right away
+	if h.stopped {
+		close(client.events)
+		return nil, false, latest
+	}
+
+	h.clients[client.id] = client
+	log.Printf("SSE client connected: %s (total: %d)", client.id, len(h.clients))
+	return replay, resync, latest
+}
+
+// unsubscribe removes a client and closes its event channel
+func (h *SSEHub) unsubscribe(client *SSEClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[client.id]; ok {
+		delete(h.clients, client.id)
+		close(client.events)
+		log.Printf("SSE client disconnected: %s (total: %d)", client.id, len(h.clients))
+	}
+}
+
+// setFilter replaces the filter of a connected client
+func (h *SSEHub) setFilter(client *SSEClient, filter *beads.Filter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	client.filter = filter
+}
+
+// latestID returns the ID of the newest broadcast event
+func (h *SSEHub) latestID() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.lastID()
+}
+
+// Stop stops the SSE hub and closes every client's event channel, which ends
+// their streams so a graceful shutdown doesn't wait on them
 func (h *SSEHub) Stop() {
-	close(h.stop)
+	h.stopOnce.Do(func() {
+		close(h.stop)
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.stopped = true
+		for id, client := range h.clients {
+			delete(h.clients, id)
+			close(client.events)
+		}
+	})
 }
 
 // Broadcast sends an event to all connected clients
@@ -107,48 +267,132 @@ func (h *SSEHub) Broadcast(event SSEEvent) {
 	select {
 	case h.broadcast <- event:
 	default:
+		h.dropped.Add(1)
 		log.Printf("SSE broadcast buffer full, dropping event")
 	}
 }
 
+// Clients returns the number of connected clients
+func (h *SSEHub) Clients() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// Dropped returns how many events were dropped because the broadcast buffer
+// or a slow client's queue was full
+func (h *SSEHub) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+// resyncEvent tells a client it missed events and should refetch everything.
+// It carries the newest event ID so the client resumes from there.
+func resyncEvent(reason, latest string) SSEEvent {
+	return SSEEvent{
+		Type: "resync",
+		Data: map[string]interface{}{
+			"timestamp": time.Now().Format(time.RFC3339),
+			"reason":    reason,
+		},
+		ID: latest,
+	}
+}
+
+// writeResync writes a resync event followed by a reload carrying the same
+// instruction and the current stats, which dashboards without a resync
+// listener act on. Clients that handle resync can ignore a reload marked
+// with "resync".
+func writeResync(w http.ResponseWriter, reason, latest string, stats *beads.Stats) error {
+	if err := writeSSE(w, resyncEvent(reason, latest)); err != nil {
+		return err
+	}
+	return writeSSE(w, SSEEvent{
+		Type: "reload",
+		Data: map[string]interface{}{
+			"timestamp": time.Now().Format(time.RFC3339),
+			"stats":     stats,
+			"resync":    reason,
+		},
+	})
+}
+
+// writeSSE writes one event in the text/event-stream format
+func writeSSE(w http.ResponseWriter, event SSEEvent) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		log.Printf("SSE marshal error: %v", err)
+		return nil
+	}
+	if event.ID != "" {
+		fmt.Fprintf(w, "id: %s\n", event.ID)
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
 // GET /api/events - SSE endpoint
 func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
+	p := s.project(r)
+
+	// Get flusher
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "SSE not supported", http.StatusInternalServerError)
+		return
+	}
+
 	// Set headers for SSE
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("X-Accel-Buffering", "no")
 
-	// Create client
+	// Browsers send Last-Event-ID when they reconnect; scripts can use the query
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	lastEventID = strings.TrimSpace(lastEventID)
+
+	// Create and register client. Filters use the same parameters as /api/beads.
 	client := &SSEClient{
 		id:     fmt.Sprintf("%d", time.Now().UnixNano()),
 		events: make(chan SSEEvent, 64),
+		filter: parseFilter(r.URL.Query()),
 	}
-
-	// Register client
-	s.sse.register <- client
+	replay, resync, latest := p.sse.subscribe(client, lastEventID)
 
 	// Ensure client is unregistered on disconnect
-	defer func() {
-		s.sse.unregister <- client
-	}()
+	defer p.sse.unsubscribe(client)
 
-	// Get flusher
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		http.Error(w, "SSE not supported", http.StatusInternalServerError)
-		return
-	}
+	// The stream outlives the server's read and write timeouts. Clearing the
+	// read deadline also stops the server cancelling the request context when
+	// it expires; writes get their own deadline each time.
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
 
-	// Send initial state
+	// Send initial state. A fresh client resumes from the newest event.
 	initialEvent := SSEEvent{
 		Type: "init",
 		Data: map[string]interface{}{
-			"stats": s.graph.GetStats(),
+			"stats": p.Graph.Snapshot().GetStats(),
 		},
 	}
 	data, _ := json.Marshal(initialEvent)
+	if lastEventID == "" && latest != "" {
+		fmt.Fprintf(w, "id: %s\n", latest)
+	}
 	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", initialEvent.Type, data)
+
+	if resync {
+		writeResync(w, "gap", latest, p.Graph.Snapshot().GetStats())
+	}
+	for _, event := range replay {
+		if err := writeSSE(w, event); err != nil {
+			return
+		}
+	}
 	flusher.Flush()
 
 	// Listen for events
@@ -161,12 +405,17 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 			if !ok {
 				return
 			}
-			data, err := json.Marshal(event.Data)
-			if err != nil {
-				log.Printf("SSE marshal error: %v", err)
-				continue
+			rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := writeSSE(w, event); err != nil {
+				log.Printf("SSE write error: %v", err)
+				return
+			}
+
+			// Events were dropped while this client was slow. Once the
+			// backlog is drained, tell it to refetch rather than resume.
+			if len(client.events) == 0 && client.behind.CompareAndSwap(true, false) {
+				writeResync(w, "behind", p.sse.latestID(), p.Graph.Snapshot().GetStats())
 			}
-			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
 			flusher.Flush()
 		}
 	}
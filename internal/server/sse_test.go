@@ -0,0 +1,465 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taylorkpotter/seeBeads/internal/beads"
+)
+
+// rememberEvents adds n events of one generation to the hub's replay ring, as
+// Run does for broadcasts, and returns their IDs
+func rememberEvents(h *SSEHub, generation uint64, n int) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ids := make([]string, n)
+	for i := range ids {
+		event := SSEEvent{Type: "reload", Data: map[string]interface{}{"n": i}, Generation: generation}
+		event.ID = h.nextID(generation)
+		h.remember(event)
+		ids[i] = event.ID
+	}
+	return ids
+}
+
+func newTestClient() *SSEClient {
+	return &SSEClient{id: "test", events: make(chan SSEEvent, 64)}
+}
+
+func TestSSEHubReplaysEventsAfterLastEventID(t *testing.T) {
+	h := NewSSEHub(time.Hour)
+	ids := rememberEvents(h, 1, 3)
+	ids = append(ids, rememberEvents(h, 2, 2)...)
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		want        []string
+	}{
+		{"fresh client", "", nil},
+		{"up to date", ids[4], nil},
+		{"within a generation", ids[1], ids[2:]},
+		{"across generations", ids[2], ids[3:]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient()
+			replay, resync, latest := h.subscribe(client, tt.lastEventID)
+			defer h.unsubscribe(client)
+			if resync {
+				t.Error("resync requested for an ID in the ring")
+			}
+			if latest != ids[4] {
+				t.Errorf("latest = %q, want %q", latest, ids[4])
+			}
+			var got []string
+			for _, event := range replay {
+				got = append(got, event.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("replayed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSSEHubResyncsForEvictedEventID(t *testing.T) {
+	h := NewSSEHub(time.Hour)
+	ids := rememberEvents(h, 1, replayBufferSize+5)
+
+	for _, lastEventID := range []string{ids[0], ids[4], "0-1-0", "garbage"} {
+		client := newTestClient()
+		replay, resync, latest := h.subscribe(client, lastEventID)
+		h.unsubscribe(client)
+		if !resync {
+			t.Errorf("%q: no resync", lastEventID)
+		}
+		if len(replay) != 0 {
+			t.Errorf("%q: replayed %d events after a gap", lastEventID, len(replay))
+		}
+		if latest != ids[len(ids)-1] {
+			t.Errorf("%q: latest = %q", lastEventID, latest)
+		}
+	}
+
+	// The oldest event still in the ring can be resumed from
+	client := newTestClient()
+	replay, resync, _ := h.subscribe(client, ids[5])
+	h.unsubscribe(client)
+	if resync || len(replay) != replayBufferSize-1 {
+		t.Errorf("resume from oldest kept event: resync = %v, replayed %d", resync, len(replay))
+	}
+}
+
+func TestSSEHubMarksSlowClientsBehind(t *testing.T) {
+	h := NewSSEHub(time.Hour)
+	go h.Run()
+	defer h.Stop()
+
+	client := &SSEClient{id: "slow", events: make(chan SSEEvent, 1)}
+	h.subscribe(client, "")
+	for i := 0; i < 3; i++ {
+		h.Broadcast(SSEEvent{Type: "reload", Generation: uint64(i + 1)})
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for h.Dropped() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("dropped = %d, want 2", h.Dropped())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !client.behind.Load() {
+		t.Error("client not marked behind after events were dropped")
+	}
+}
+
+// readSSEEvents reads events from a stream until want events have arrived,
+// returning "type id" for each
+func readSSEEvents(t *testing.T, url, lastEventID string, want int) []string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var events []string
+	var id string
+	scanner := bufio.NewScanner(resp.Body)
+	for len(events) < want && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			events = append(events, strings.TrimPrefix(line, "event: ")+" "+id)
+			id = ""
+		}
+	}
+	if len(events) < want {
+		t.Fatalf("got events %v, want %d: %v", events, want, scanner.Err())
+	}
+	return events
+}
+
+func TestSSEHandlerLastEventID(t *testing.T) {
+	s, ts := newTestServer(t)
+	ids := rememberEvents(s.projects[0].sse, 2, 3)
+
+	tests := []struct {
+		name        string
+		query       string
+		lastEventID string
+		want        []string
+	}{
+		{"fresh client resumes from the newest event", "", "", []string{"init " + ids[2]}},
+		{"header replays missed events", "", ids[0], []string{"init ", "reload " + ids[1], "reload " + ids[2]}},
+		{"query replays missed events", "?lastEventId=" + ids[1], "", []string{"init ", "reload " + ids[2]}},
+		{"unknown ID resyncs", "", "0-1-0", []string{"init ", "resync " + ids[2], "reload "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := readSSEEvents(t, ts.URL+"/api/events"+tt.query, tt.lastEventID, len(tt.want))
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("events = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBroadcastReloadFollowsTypedEvents(t *testing.T) {
+	s, _ := newTestServer(t)
+	p := s.projects[0]
+	client := newTestClient()
+	p.sse.subscribe(client, "")
+	defer p.sse.unsubscribe(client)
+
+	f, err := os.OpenFile(s.config.JSONLPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(f, `{"id":"bd-2","title":"two","status":"open","created_at":"2026-01-02T00:00:00Z","updated_at":"2026-01-02T00:00:00Z"}`)
+	f.Close()
+	if err := p.Graph.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	p.broadcastReload()
+
+	// Dashboards that only know "reload" still refetch; newer ones see typed
+	var got []string
+	for len(got) < 2 {
+		select {
+		case event := <-client.events:
+			if event.Type == "reload" {
+				data := event.Data.(map[string]interface{})
+				got = append(got, fmt.Sprintf("reload typed=%v", data["typed"]))
+			} else {
+				got = append(got, event.Type)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got events %v, want 2", got)
+		}
+	}
+	if want := "bead.created,reload typed=1"; strings.Join(got, ",") != want {
+		t.Errorf("events = %s, want %s", strings.Join(got, ","), want)
+	}
+}
+
+// labeledBead returns a JSONL record for a bead with one label
+func labeledBead(id, title, status, label string) string {
+	return fmt.Sprintf(`{"id":%q,"title":%q,"status":%q,"labels":[%q],"created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}`+"\n",
+		id, title, status, label)
+}
+
+// changeEvents loads before, replaces the file with after and returns the
+// events broadcastReload sends for the changes: one per change, then a reload
+func changeEvents(t *testing.T, before, after []string) []SSEEvent {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "issues.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(before, "")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	graph, err := beads.BuildGraph(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(after, "")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := graph.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+
+	snap := graph.Snapshot()
+	var events []SSEEvent
+	for _, change := range snap.Changes {
+		events = append(events, SSEEvent{Type: string(change.Type), Data: change, Generation: snap.Generation})
+	}
+	return append(events, SSEEvent{Type: "reload", Data: map[string]interface{}{}, Generation: snap.Generation})
+}
+
+// filterChanges are bd-1 closing, bd-2 being renamed and bd-4 being created;
+// bd-3 doesn't change
+var filterChanges = [][]string{
+	{
+		labeledBead("bd-1", "one", "open", "ui"),
+		labeledBead("bd-2", "two", "open", "api"),
+		labeledBead("bd-3", "three", "closed", "ui"),
+	},
+	{
+		labeledBead("bd-1", "one", "closed", "ui"),
+		labeledBead("bd-2", "two again", "open", "api"),
+		labeledBead("bd-3", "three", "closed", "ui"),
+		labeledBead("bd-4", "four", "open", "ui"),
+	},
+}
+
+// describeEvent returns "type id" for change events and the type for others
+func describeEvent(event SSEEvent) string {
+	if change, ok := event.Data.(*beads.Change); ok {
+		return event.Type + " " + change.ID
+	}
+	return event.Type
+}
+
+func TestSSEHubFiltersChangeEvents(t *testing.T) {
+	events := changeEvents(t, filterChanges[0], filterChanges[1])
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"unfiltered", "", []string{"bead.closed bd-1", "bead.updated bd-2", "bead.created bd-4", "reload"}},
+		{"label", "labels=ui", []string{"bead.closed bd-1", "bead.created bd-4", "reload"}},
+		{"ids", "ids=bd-2,bd-3", []string{"bead.updated bd-2", "reload"}},
+		// bd-1 leaves the filter, which only its previous state matches
+		{"status left", "status=open", []string{"bead.closed bd-1", "bead.updated bd-2", "bead.created bd-4", "reload"}},
+		{"status entered", "status=closed", []string{"bead.closed bd-1", "reload"}},
+		{"nothing matches", "status=in_progress", []string{"reload"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewSSEHub(time.Hour)
+			go h.Run()
+			defer h.Stop()
+
+			query, _ := url.ParseQuery(tt.query)
+			client := newTestClient()
+			client.filter = parseFilter(query)
+			h.subscribe(client, "")
+			for _, event := range events {
+				h.Broadcast(event)
+			}
+
+			// The reload goes to everyone and comes last
+			var got []string
+			for len(got) == 0 || got[len(got)-1] != "reload" {
+				select {
+				case event := <-client.events:
+					got = append(got, describeEvent(event))
+				case <-time.After(5 * time.Second):
+					t.Fatalf("got %v, no reload", got)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSSEHubFiltersReplay(t *testing.T) {
+	h := NewSSEHub(time.Hour)
+	first := rememberEvents(h, 1, 1)[0]
+	h.mu.Lock()
+	for _, event := range changeEvents(t, filterChanges[0], filterChanges[1]) {
+		event.ID = h.nextID(event.Generation)
+		h.remember(event)
+	}
+	h.mu.Unlock()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"bead.closed bd-1", "bead.updated bd-2", "bead.created bd-4", "reload"}},
+		{"labels=api", []string{"bead.updated bd-2", "reload"}},
+		{"status=open&labels=ui", []string{"bead.closed bd-1", "bead.created bd-4", "reload"}},
+	}
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		client := newTestClient()
+		client.filter = parseFilter(query)
+		replay, resync, _ := h.subscribe(client, first)
+		h.unsubscribe(client)
+		if resync {
+			t.Errorf("%q: resync requested", tt.query)
+		}
+		var got []string
+		for _, event := range replay {
+			got = append(got, describeEvent(event))
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%q: replayed %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestStopEndsEventStreams(t *testing.T) {
+	s, _ := newTestServer(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.httpServer = &http.Server{Handler: s.handler()}
+	go s.httpServer.Serve(listener)
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	ended := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, resp.Body)
+		ended <- err
+	}()
+
+	// Shutdown waits for active requests, so it only returns once the
+	// stream has ended
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	select {
+	case err := <-ended:
+		if err != nil {
+			t.Errorf("stream ended with %v, want EOF", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("stream still open after Stop")
+	}
+}
+
+func TestSSEHeartbeatInterval(t *testing.T) {
+	h := NewSSEHub(20 * time.Millisecond)
+	go h.Run()
+	defer h.Stop()
+	client := newTestClient()
+	h.subscribe(client, "")
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		select {
+		case event := <-client.events:
+			if event.Type != "heartbeat" || event.ID != "" {
+				t.Fatalf("got %s event with ID %q, want a heartbeat without one", event.Type, event.ID)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d heartbeats in 5s", i)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("3 heartbeats in %s, faster than the interval", elapsed)
+	}
+
+	if h := NewSSEHub(0); h.heartbeat != defaultHeartbeat {
+		t.Errorf("default heartbeat = %s, want %s", h.heartbeat, defaultHeartbeat)
+	}
+}
+
+func TestSSEDropsStalledClient(t *testing.T) {
+	defer func(timeout time.Duration) { streamWriteTimeout = timeout }(streamWriteTimeout)
+	streamWriteTimeout = 100 * time.Millisecond
+
+	s, ts := newTestServer(t)
+	p := s.projects[0]
+
+	// A client that sends the request and never reads the response
+	conn, err := net.Dial("tcp", strings.TrimPrefix(ts.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET /api/events HTTP/1.1\r\nHost: 127.0.0.1\r\n\r\n")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for p.sse.Clients() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("client never connected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Fill the socket buffers until a write blocks past its deadline
+	padding := strings.Repeat("x", 256<<10)
+	for generation := uint64(1); p.sse.Clients() > 0; generation++ {
+		if time.Now().After(deadline.Add(10 * time.Second)) {
+			t.Fatal("stalled client still connected")
+		}
+		p.sse.Broadcast(SSEEvent{Type: "reload", Data: map[string]string{"padding": padding}, Generation: generation})
+		time.Sleep(time.Millisecond)
+	}
+}
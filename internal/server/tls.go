@@ -0,0 +1,181 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/taylorkpotter/seeBeads/internal/beads"
+)
+
+// Self-signed development certificates are cached in the private .seebeads
+// directory in .beads, which git ignores, and regenerated when they near
+// expiry. Each set of hosts gets its own pair of files, so instances serving
+// different hosts don't keep replacing each other's certificate.
+const (
+	selfSignedCertPrefix = "cert-"
+	selfSignedKeyPrefix  = "key-"
+	selfSignedValidity   = 365 * 24 * time.Hour
+	selfSignedRenewal    = 7 * 24 * time.Hour
+)
+
+// tlsCertificate returns the certificate to serve with, generating a
+// self-signed one if configured to
+func (s *Server) tlsCertificate() (tls.Certificate, error) {
+	if !s.config.TLSSelfSigned {
+		return tls.LoadX509KeyPair(s.config.TLSCert, s.config.TLSKey)
+	}
+	dir, err := beads.PrivateDir(s.config.BeadsPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return ensureSelfSignedCert(dir, certHosts(s.config.Host))
+}
+
+// selfSignedFiles returns the cached certificate and key files for a set of
+// hosts, named after a hash of the hosts in any order
+func selfSignedFiles(dir string, hosts []string) (certFile, keyFile string) {
+	names := make([]string, len(hosts))
+	for i, host := range hosts {
+		names[i] = strings.ToLower(host)
+	}
+	sort.Strings(names)
+	sum := sha256.Sum256([]byte(strings.Join(names, "\n")))
+	id := hex.EncodeToString(sum[:6])
+	return filepath.Join(dir, selfSignedCertPrefix+id+".pem"), filepath.Join(dir, selfSignedKeyPrefix+id+".pem")
+}
+
+// certHosts lists the names a development certificate should cover
+func certHosts(host string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	extra := []string{host}
+	if name, err := os.Hostname(); err == nil {
+		extra = append(extra, name)
+	}
+	for _, name := range extra {
+		if ip := net.ParseIP(name); name == "" || (ip != nil && ip.IsUnspecified()) || containsHost(hosts, name) {
+			continue
+		}
+		hosts = append(hosts, name)
+	}
+	return hosts
+}
+
+// containsHost reports whether hosts includes name, ignoring case
+func containsHost(hosts []string, name string) bool {
+	for _, host := range hosts {
+		if strings.EqualFold(host, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureSelfSignedCert returns the cached certificate in dir for hosts,
+// creating a new one if needed. A new pair is served from memory, so a
+// concurrent instance replacing the files can't mismatch it; on disk each
+// file is replaced atomically, and a mismatched pair is regenerated.
+func ensureSelfSignedCert(dir string, hosts []string) (tls.Certificate, error) {
+	certFile, keyFile := selfSignedFiles(dir, hosts)
+	if pair, ok := cachedCert(certFile, keyFile, hosts); ok {
+		return pair, nil
+	}
+
+	log.Printf("Generating self-signed certificate %s", certFile)
+	certPEM, keyPEM, err := generateSelfSignedCert(hosts)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := beads.WriteFileAtomic(keyFile, keyPEM, 0600); err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := beads.WriteFileAtomic(certFile, certPEM, 0644); err != nil {
+		return tls.Certificate{}, err
+	}
+	return pair, nil
+}
+
+// cachedCert loads a cached pair, reporting whether it loaded, its key
+// matches, it isn't about to expire, and it covers every host
+func cachedCert(certFile, keyFile string, hosts []string) (tls.Certificate, bool) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, false
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return tls.Certificate{}, false
+	}
+	if time.Now().Add(selfSignedRenewal).After(cert.NotAfter) {
+		return tls.Certificate{}, false
+	}
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			return tls.Certificate{}, false
+		}
+	}
+	return pair, true
+}
+
+// generateSelfSignedCert creates a PEM-encoded ECDSA certificate and key
+func generateSelfSignedCert(hosts []string) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"seeBeads development"}, CommonName: hosts[0]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
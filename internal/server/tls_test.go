@@ -0,0 +1,107 @@
+package server
+
+import (
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/taylorkpotter/seeBeads/internal/beads"
+)
+
+func TestSelfSignedCertIsCachedInThePrivateDir(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.config.TLSSelfSigned = true
+
+	first, err := s.tlsCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(s.config.BeadsPath, beads.PrivateDirName)
+	_, keyFile := selfSignedFiles(dir, certHosts(s.config.Host))
+	info, err := os.Stat(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("key mode = %04o, want 0600", mode)
+	}
+	ignore, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err != nil || !strings.Contains(string(ignore), "*") {
+		t.Errorf("private directory doesn't ignore itself: %q, %v", ignore, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if name := entry.Name(); strings.HasPrefix(name, ".") && name != ".gitignore" {
+			t.Errorf("left temp file %s", name)
+		}
+	}
+
+	// The cached pair is reused
+	again, err := s.tlsCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first.Certificate[0]) != string(again.Certificate[0]) {
+		t.Error("certificate regenerated")
+	}
+}
+
+func TestSelfSignedCertPerHostSet(t *testing.T) {
+	dir := t.TempDir()
+	local := []string{"localhost", "127.0.0.1", "::1"}
+	lan := append(append([]string(nil), local...), "192.0.2.1")
+
+	first, err := ensureSelfSignedCert(dir, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ensureSelfSignedCert(dir, lan); err != nil {
+		t.Fatal(err)
+	}
+
+	// Serving other hosts leaves the first certificate in place
+	again, err := ensureSelfSignedCert(dir, []string{"::1", "LOCALHOST", "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first.Certificate[0]) != string(again.Certificate[0]) {
+		t.Error("certificate for the first host set was replaced")
+	}
+	localCert, _ := selfSignedFiles(dir, local)
+	lanCert, _ := selfSignedFiles(dir, lan)
+	if localCert == lanCert {
+		t.Fatalf("host sets share %s", localCert)
+	}
+
+	// A cert and key that don't match, as a racing instance could leave
+	// them, are replaced by a matching pair
+	other := t.TempDir()
+	if _, err := ensureSelfSignedCert(other, local); err != nil {
+		t.Fatal(err)
+	}
+	otherCert, _ := selfSignedFiles(other, local)
+	data, err := os.ReadFile(otherCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(localCert, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	pair, err := ensureSelfSignedCert(dir, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := selfSignedFiles(dir, local)
+	if _, ok := cachedCert(certFile, keyFile, local); !ok {
+		t.Error("mismatched pair not regenerated")
+	}
+	if cert.VerifyHostname("localhost") != nil {
+		t.Error("regenerated certificate doesn't cover localhost")
+	}
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Clients only send small control messages
+const wsMaxMessageSize = 64 * 1024
+
+// wsUpgrader performs the opening handshake. Cross-site handshakes are
+// refused earlier, by protect, so the origin isn't checked again here.
+var wsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsMessage is a message sent to WebSocket clients. Hub events keep the same
+// type, ID and data they have over SSE.
+type wsMessage struct {
+	Type string      `json:"type"`
+	ID   string      `json:"id,omitempty"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// wsRequest is a message from a WebSocket client:
+//
+//	{"type": "subscribe", "filter": {"status": "open,in_progress", "assignee": "alice"}}
+//	{"type": "agent-mode", "enabled": true}
+//	{"type": "ping"}
+//
+// Filter keys and values are the same as the /api/beads query parameters.
+type wsRequest struct {
+	Type    string            `json:"type"`
+	Filter  map[string]string `json:"filter,omitempty"`
+	Enabled bool              `json:"enabled"`
+}
+
+// GET /api/ws - WebSocket endpoint carrying the same events as /api/events
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	p := s.project(r)
+	query := r.URL.Query()
+	role := requestRole(r)
+
+	// On failure the upgrader has already written an HTTP error response
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(wsMaxMessageSize)
+
+	client := &SSEClient{
+		id:     fmt.Sprintf("ws-%d", time.Now().UnixNano()),
+		events: make(chan SSEEvent, 64),
+		filter: parseFilter(query),
+	}
+	lastEventID := strings.TrimSpace(query.Get("lastEventId"))
+	replay, resync, latest := p.sse.subscribe(client, lastEventID)
+
+	defer p.sse.unsubscribe(client)
+
+	// Read client messages on their own goroutine so events keep flowing
+	requests := make(chan wsRequest)
+	done := make(chan struct{})
+	defer close(done)
+	go s.readWebSocket(conn, requests, done)
+
+	// Only this goroutine writes messages; the reader only sends control
+	// frames, which the connection allows concurrently
+	write := func(message wsMessage) error {
+		conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		return conn.WriteJSON(message)
+	}
+	send := func(event SSEEvent) error {
+		return write(wsMessage{Type: event.Type, ID: event.ID, Data: event.Data})
+	}
+	closeNormal := func() {
+		message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(streamWriteTimeout))
+	}
+
+	initial := SSEEvent{
+		Type: "init",
+		Data: map[string]interface{}{
+			"stats": p.Graph.Snapshot().GetStats(),
+		},
+	}
+	if lastEventID == "" {
+		initial.ID = latest
+	}
+	if err := send(initial); err != nil {
+		closeNormal()
+		return
+	}
+	if resync {
+		send(resyncEvent("gap", latest))
+	}
+	for _, event := range replay {
+		if err := send(event); err != nil {
+			closeNormal()
+			return
+		}
+	}
+
+	for {
+		select {
+		case req, ok := <-requests:
+			if !ok {
+				return
+			}
+			if err := write(s.handleWebSocketRequest(r, client, role, req)); err != nil {
+				closeNormal()
+				return
+			}
+
+		case event, ok := <-client.events:
+			if !ok {
+				closeNormal()
+				return
+			}
+			if err := send(event); err != nil {
+				log.Printf("WebSocket write error: %v", err)
+				closeNormal()
+				return
+			}
+
+			// Same as SSE: once a slow client has drained, tell it to refetch
+			if len(client.events) == 0 && client.behind.CompareAndSwap(true, false) {
+				send(resyncEvent("behind", p.sse.latestID()))
+			}
+		}
+	}
+}
+
+// readWebSocket decodes client messages until the connection ends, then
+// closes requests. Pings and the close handshake are answered while reading,
+// and messages over wsMaxMessageSize end the connection.
+func (s *Server) readWebSocket(conn *websocket.Conn, requests chan<- wsRequest, done <-chan struct{}) {
+	defer close(requests)
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			// Protocol errors and oversized messages have already been
+			// answered with a close frame
+			conn.Close()
+			return
+		}
+
+		// Malformed messages get the same error reply as unknown types
+		var req wsRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			req = wsRequest{}
+		}
+
+		select {
+		case requests <- req:
+		case <-done:
+			return
+		}
+	}
+}
+
+// handleWebSocketRequest applies a client message on the connection opened
+// by r and returns the reply. Messages that change server state need the same
+// role as the matching POST, and agent mode covers the same projects.
+func (s *Server) handleWebSocketRequest(r *http.Request, client *SSEClient, role Role, req wsRequest) wsMessage {
+	switch req.Type {
+	case "ping":
+		return wsMessage{Type: "pong", Data: map[string]interface{}{
+			"timestamp": time.Now().Format(time.RFC3339),
+		}}
+
+	case "subscribe":
+		query := url.Values{}
+		for key, value := range req.Filter {
+			query.Set(key, value)
+		}
+		s.project(r).sse.setFilter(client, parseFilter(query))
+		return wsMessage{Type: "subscribed", Data: map[string]interface{}{
+			"filter": req.Filter,
+		}}
+
+	case "agent-mode":
+		if !role.allows(http.MethodPost) {
+			return wsMessage{Type: "error", Data: map[string]string{
+				"error": "This token is read-only",
+			}}
+		}
+		s.setAgentMode(r, req.Enabled)
+		return wsMessage{Type: "agent-mode", Data: map[string]bool{
+			"agentMode": req.Enabled,
+		}}
+	}
+
+	return wsMessage{Type: "error", Data: map[string]string{
+		"error": "Invalid or unknown message type",
+	}}
+}
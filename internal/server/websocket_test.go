@@ -0,0 +1,219 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialWebSocket connects to /api/ws and reads the init message
+func dialWebSocket(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	conn, _ := dialWebSocketInit(t, url, "", nil)
+	return conn
+}
+
+// dialWebSocketInit connects to /api/ws with the given query and headers and
+// returns the connection and its init message
+func dialWebSocketInit(t *testing.T, url, query string, header http.Header) (*websocket.Conn, wsMessage) {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/api/ws"+query, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	init := readWebSocketMessage(t, conn)
+	if init.Type != "init" {
+		t.Fatalf("first message = %q, want init", init.Type)
+	}
+	return conn, init
+}
+
+func readWebSocketMessage(t *testing.T, conn *websocket.Conn) wsMessage {
+	t.Helper()
+	var message wsMessage
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func TestWebSocketRequests(t *testing.T) {
+	_, ts := newTestServer(t)
+	conn := dialWebSocket(t, ts.URL)
+
+	tests := []struct {
+		request string
+		reply   string
+	}{
+		{`{"type":"ping"}`, "pong"},
+		{`{"type":"subscribe","filter":{"status":"open"}}`, "subscribed"},
+		{`{"type":"unknown"}`, "error"},
+		{`not json`, "error"},
+	}
+	for _, tt := range tests {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(tt.request)); err != nil {
+			t.Fatal(err)
+		}
+		if message := readWebSocketMessage(t, conn); message.Type != tt.reply {
+			t.Errorf("%s: reply = %q, want %q", tt.request, message.Type, tt.reply)
+		}
+	}
+}
+
+func TestWebSocketControlFrames(t *testing.T) {
+	_, ts := newTestServer(t)
+	conn := dialWebSocket(t, ts.URL)
+
+	// Pings are answered with the same payload
+	pong := make(chan string, 1)
+	conn.SetPongHandler(func(data string) error {
+		pong <- data
+		return nil
+	})
+	if err := conn.WriteControl(websocket.PingMessage, []byte("hello"), time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"ping"}`))
+	readWebSocketMessage(t, conn) // Control frames are handled while reading
+	select {
+	case data := <-pong:
+		if data != "hello" {
+			t.Errorf("pong payload = %q", data)
+		}
+	default:
+		t.Error("ping not answered")
+	}
+
+	// Closing is acknowledged with the same code
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye")
+	if err := conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("after close, read error = %v", err)
+	}
+}
+
+func TestWebSocketOversizedMessage(t *testing.T) {
+	_, ts := newTestServer(t)
+	conn := dialWebSocket(t, ts.URL)
+
+	big := `{"type":"ping","filter":{"x":"` + strings.Repeat("x", wsMaxMessageSize) + `"}}`
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(big)); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+		t.Errorf("read error = %v, want close %d", err, websocket.CloseMessageTooBig)
+	}
+}
+
+func TestWebSocketCrossOriginHandshake(t *testing.T) {
+	_, ts := newTestServer(t)
+	header := http.Header{"Origin": {"https://evil.example"}}
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/ws", header)
+	if err == nil {
+		t.Fatal("cross-origin handshake accepted")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("response = %v, want 403", resp)
+	}
+}
+
+func TestWebSocketInit(t *testing.T) {
+	s, ts := newTestServer(t)
+	ids := rememberEvents(s.projects[0].sse, 2, 3)
+
+	tests := []struct {
+		name   string
+		query  string
+		initID string
+		then   []string // Messages after init, as "type id"
+	}{
+		{"fresh client resumes from the newest event", "", ids[2], nil},
+		{"missed events are replayed", "?lastEventId=" + ids[0], "", []string{"reload " + ids[1], "reload " + ids[2]}},
+		{"unknown ID resyncs", "?lastEventId=0-1-0", "", []string{"resync " + ids[2]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, init := dialWebSocketInit(t, ts.URL, tt.query, nil)
+			if init.ID != tt.initID {
+				t.Errorf("init ID = %q, want %q", init.ID, tt.initID)
+			}
+			data, _ := init.Data.(map[string]interface{})
+			stats, _ := data["stats"].(map[string]interface{})
+			if stats["total"] != float64(1) {
+				t.Errorf("init data = %v, want stats for one bead", init.Data)
+			}
+
+			var got []string
+			for range tt.then {
+				message := readWebSocketMessage(t, conn)
+				got = append(got, message.Type+" "+message.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.then, ",") {
+				t.Errorf("messages = %v, want %v", got, tt.then)
+			}
+		})
+	}
+}
+
+func TestWebSocketPing(t *testing.T) {
+	_, ts := newTestServer(t)
+	conn := dialWebSocket(t, ts.URL)
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"ping"}`)); err != nil {
+		t.Fatal(err)
+	}
+	message := readWebSocketMessage(t, conn)
+	data, _ := message.Data.(map[string]interface{})
+	timestamp, _ := data["timestamp"].(string)
+	if _, err := time.Parse(time.RFC3339, timestamp); message.Type != "pong" || err != nil {
+		t.Errorf("reply = %+v, want a pong with a timestamp", message)
+	}
+}
+
+func TestWebSocketAgentModeRoles(t *testing.T) {
+	s, _ := newTestServer(t)
+	tokens, err := loadTokens(writeTokens(t, readToken+" read-only\n"+writeToken+" read-write\n", 0600))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.tokens = tokens
+	p := s.projects[0]
+	if p.watcher, err = p.newWatcher(false); err != nil {
+		t.Fatal(err)
+	}
+	defer p.watcher.Stop()
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	tests := []struct {
+		token     string
+		reply     string
+		agentMode bool
+	}{
+		{readToken, "error", false},
+		{writeToken, "agent-mode", true},
+	}
+	for _, tt := range tests {
+		conn, _ := dialWebSocketInit(t, ts.URL, "", http.Header{"Authorization": {"Bearer " + tt.token}})
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"agent-mode","enabled":true}`)); err != nil {
+			t.Fatal(err)
+		}
+		if message := readWebSocketMessage(t, conn); message.Type != tt.reply {
+			t.Errorf("%s token: reply = %+v, want %s", tt.token[:1], message, tt.reply)
+		}
+		if got := p.watcher.AgentMode(); got != tt.agentMode {
+			t.Errorf("%s token: agent mode = %v, want %v", tt.token[:1], got, tt.agentMode)
+		}
+	}
+}
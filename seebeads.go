@@ -19,7 +19,19 @@ import (
 	"github.com/taylorkpotter/seeBeads/internal/server"
 )
 
-// Handler returns an http.Handler that serves the seeBeads dashboard.
+// Options configures an embedded dashboard
+type Options struct {
+	// Writable lets the dashboard create and edit beads. The handler doesn't
+	// authenticate anyone, so only enable this behind the host application's
+	// own authentication.
+	Writable bool
+
+	// AllowedOrigins may make cross-origin requests, and changes if Writable,
+	// besides the host application's own origin
+	AllowedOrigins []string
+}
+
+// Handler returns an http.Handler that serves a read-only seeBeads dashboard.
 //
 // Parameters:
 //   - jsonlPath: path to beads.jsonl file. Empty string auto-discovers from cwd.
@@ -33,6 +45,14 @@ import (
 //	// Explicit path, mount at /dashboard/
 //	http.Handle("/dashboard/", seebeads.Handler("/app/.beads/beads.jsonl", "/dashboard"))
 func Handler(jsonlPath, basePath string) http.Handler {
+	return HandlerWithOptions(jsonlPath, basePath, Options{})
+}
+
+// HandlerWithOptions is like Handler, with options such as making the
+// dashboard writable:
+//
+//	http.Handle("/beads/", requireLogin(seebeads.HandlerWithOptions("", "/beads", seebeads.Options{Writable: true})))
+func HandlerWithOptions(jsonlPath, basePath string, opts Options) http.Handler {
 	// Auto-discover if not specified
 	if jsonlPath == "" {
 		var err error
@@ -49,7 +69,7 @@ func Handler(jsonlPath, basePath string) http.Handler {
 	}
 
 	// Create embedded server handler
-	handler := server.NewHandler(graph, jsonlPath, basePath)
+	handler := server.NewHandler(graph, jsonlPath, basePath, opts.serverOptions())
 	return handler
 }
 
@@ -62,7 +82,7 @@ type Dashboard struct {
 	watcher  *beads.Watcher
 }
 
-// New creates a new Dashboard with full lifecycle control.
+// New creates a new read-only Dashboard with full lifecycle control.
 //
 // Example:
 //
@@ -73,6 +93,11 @@ type Dashboard struct {
 //	defer dash.Close()
 //	http.Handle("/beads/", dash)
 func New(jsonlPath, basePath string) (*Dashboard, error) {
+	return NewWithOptions(jsonlPath, basePath, Options{})
+}
+
+// NewWithOptions is like New, with options such as making the dashboard writable
+func NewWithOptions(jsonlPath, basePath string, opts Options) (*Dashboard, error) {
 	// Auto-discover if not specified
 	if jsonlPath == "" {
 		var err error
@@ -87,7 +112,7 @@ func New(jsonlPath, basePath string) (*Dashboard, error) {
 		return nil, fmt.Errorf("failed to parse beads: %w", err)
 	}
 
-	handler := server.NewHandler(graph, jsonlPath, basePath)
+	handler := server.NewHandler(graph, jsonlPath, basePath, opts.serverOptions())
 
 	return &Dashboard{
 		handler:  handler,
@@ -112,7 +137,11 @@ func (d *Dashboard) Close() error {
 
 // Stats returns current bead statistics
 func (d *Dashboard) Stats() *beads.Stats {
-	return d.graph.GetStats()
+	return d.graph.Snapshot().GetStats()
+}
+
+func (o Options) serverOptions() server.HandlerOptions {
+	return server.HandlerOptions{Writable: o.Writable, AllowedOrigins: o.AllowedOrigins}
 }
 
 // findBeadsJSONL searches for .beads/beads.jsonl starting from cwd
@@ -75,6 +75,7 @@ export async function getHealth(): Promise<{
   status: string
   version: string
   beadsFile: string
+  backend: string
   lastUpdated: string
   totalBeads: number
 }> {
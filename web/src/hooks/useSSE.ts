@@ -1,6 +1,7 @@
 import { useEffect, useRef, useState, useCallback } from 'react'
-import { useQueryClient } from '@tanstack/react-query'
-import { Stats } from '../types'
+import { QueryKey, useQueryClient } from '@tanstack/react-query'
+import { getStats } from '../api/client'
+import { Bead, BeadDetailResponse, BeadsResponse, Dependency, Stats } from '../types'
 
 interface SSEState {
   connected: boolean
@@ -8,6 +9,31 @@ interface SSEState {
   lastUpdate: Date | null
 }
 
+// Typed events the server sends instead of 'reload' when only a few beads changed
+const CHANGE_EVENTS = [
+  'bead.created',
+  'bead.updated',
+  'bead.closed',
+  'bead.reopened',
+  'bead.deleted',
+  'dependency.added',
+  'dependency.removed',
+]
+
+interface ChangeEvent {
+  type: string
+  id: string
+  bead: Bead
+  fields?: Record<string, unknown>
+  dependency?: Dependency
+}
+
+// Queries holding lists of beads, patched in place when a bead changes
+const LIST_QUERIES = ['beads', 'recent-beads', 'open-beads-all', 'beads-timeline']
+
+// Fields that can move a bead in or out of a filtered list or change its order
+const LIST_FIELDS = ['status', 'priority', 'issue_type', 'labels', 'assignee']
+
 export function useSSE() {
   const [state, setState] = useState<SSEState>({
     connected: false,
@@ -19,20 +45,94 @@ export function useSSE() {
   const eventSourceRef = useRef<EventSource | null>(null)
   const reconnectTimeoutRef = useRef<number | null>(null)
   const debounceTimeoutRef = useRef<number | null>(null)
+  // Queries to invalidate once events stop arriving; 'all' after a reload
+  const pendingRef = useRef<QueryKey[] | 'all'>([])
   
-  // Debounced invalidation using ref to avoid dependency issues
-  const invalidateQueries = useCallback(() => {
+  // Debounced invalidation using ref to avoid dependency issues. Without
+  // keys, everything is refetched; with keys, only those queries are, and
+  // stats are fetched again since typed events don't carry them.
+  const invalidateQueries = useCallback((keys?: QueryKey[]) => {
+    if (!keys) {
+      pendingRef.current = 'all'
+    } else if (pendingRef.current !== 'all') {
+      pendingRef.current.push(...keys)
+    }
     if (debounceTimeoutRef.current) {
       clearTimeout(debounceTimeoutRef.current)
     }
     debounceTimeoutRef.current = window.setTimeout(() => {
+      const pending = pendingRef.current
+      pendingRef.current = []
       if (import.meta.env.DEV) {
-        console.log('[SSE] Invalidating queries')
+        console.log('[SSE] Invalidating queries:', pending)
+      }
+      if (pending === 'all') {
+        queryClient.invalidateQueries()
+        return
+      }
+      for (const queryKey of pending) {
+        queryClient.invalidateQueries({ queryKey })
       }
-      queryClient.invalidateQueries()
+      getStats()
+        .then(stats => setState(prev => ({ ...prev, stats, lastUpdate: new Date() })))
+        .catch(() => {})
     }, 300) // Reduced from 500ms to 300ms
   }, [queryClient])
   
+  // Apply a typed change: merge the changed fields into cached copies of the
+  // bead right away, then refetch only what is computed from it (stats, epic
+  // progress, lists the bead may have joined or left)
+  const applyChange = useCallback((change: ChangeEvent) => {
+    const keys: QueryKey[] = [['stats'], ['epics']]
+    
+    const patch = (bead: Bead): Bead => {
+      if (bead.id !== change.id) {
+        return bead
+      }
+      return change.fields ? { ...bead, ...change.fields } as Bead : { ...bead, ...change.bead }
+    }
+    
+    if (change.type === 'bead.deleted') {
+      keys.push(['bead', change.id])
+    } else {
+      queryClient.setQueryData<BeadDetailResponse>(['bead', change.id], old =>
+        old && { ...old, bead: patch(old.bead) })
+      for (const list of LIST_QUERIES) {
+        queryClient.setQueriesData<BeadsResponse>({ queryKey: [list] }, old =>
+          old && { ...old, beads: old.beads.map(patch) })
+      }
+    }
+    
+    const fields = Object.keys(change.fields || {})
+    switch (change.type) {
+      case 'bead.updated':
+        // Cached copies are already up to date unless the bead may have
+        // moved in or out of a filtered list
+        if (fields.some(field => LIST_FIELDS.includes(field))) {
+          keys.push(...LIST_QUERIES.map(list => [list]))
+        }
+        break
+      case 'dependency.added':
+      case 'dependency.removed':
+        // Both ends list the other as a blocker or blocked bead
+        keys.push(['bead', change.id])
+        if (change.dependency) {
+          keys.push(['bead', change.dependency.depends_on_id])
+        }
+        break
+      default:
+        // Created, deleted, closed and reopened beads change list membership
+        keys.push(...LIST_QUERIES.map(list => [list]))
+    }
+    
+    // Parents show their children and their status
+    const childrenChanged = change.type !== 'bead.updated' || fields.includes('status')
+    if (change.bead.ParentID && childrenChanged && !change.type.startsWith('dependency.')) {
+      keys.push(['bead', change.bead.ParentID])
+    }
+    invalidateQueries(keys)
+  }, [queryClient, invalidateQueries])
+  
   const connect = useCallback(() => {
     if (eventSourceRef.current) {
       eventSourceRef.current.close()
@@ -102,15 +202,46 @@ export function useSSE() {
         stats: data.stats,
         lastUpdate: new Date(),
       }))
-      
+
+      // After typed events or a resync the cache is already taken care of;
+      // the reload is only for dashboards that don't handle those
+      if (data.typed || data.resync) {
+        return
+      }
+
       // Invalidate all queries to trigger refetch (debounced for bulk operations)
       invalidateQueries()
     })
     
+    // Handle 'resync' events - the server couldn't replay the events this
+    // client missed (a gap after reconnecting, or it fell behind), so
+    // nothing cached can be trusted. The event's ID resumes the stream.
+    es.addEventListener('resync', (event) => {
+      const data = JSON.parse(event.data)
+      if (import.meta.env.DEV) {
+        console.log('[SSE] resync:', data)
+      }
+      getStats()
+        .then(stats => setState(prev => ({ ...prev, stats, lastUpdate: new Date() })))
+        .catch(() => {})
+      invalidateQueries()
+    })
+
+    // Handle typed change events - patch the affected beads
+    for (const type of CHANGE_EVENTS) {
+      es.addEventListener(type, (event) => {
+        const change: ChangeEvent = JSON.parse(event.data)
+        if (import.meta.env.DEV) {
+          console.log(`[SSE] ${type}:`, change)
+        }
+        applyChange(change)
+      })
+    }
+    
     es.addEventListener('heartbeat', () => {
       // Keep-alive, no action needed
     })
-  }, [invalidateQueries])
+  }, [invalidateQueries, applyChange])
   
   useEffect(() => {
     connect()